@@ -0,0 +1,100 @@
+package quiver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// The JSON bundle format
+//
+// A whole library can be saved as a single JSON document (a "bundle"), and rebuilt from it:
+//
+//	{
+//	  "format": "quiver-bundle",
+//	  "version": 1,
+//	  "library": {
+//	    "children": [{"uuid": "NB", "children": []}],
+//	    "notebooks": [
+//	      {
+//	        "name": "Notebook",
+//	        "uuid": "NB",
+//	        "notes": [
+//	          {
+//	            "uuid": "N",
+//	            "title": "Note",
+//	            "tags": ["go"],
+//	            "created_at": 1505731210,
+//	            "updated_at": 1505731218,
+//	            "cells": [{"type": "code", "language": "go", "data": "package main"}],
+//	            "resources": [{"name": "image.png", "data": "data:image/png;base64,iVBORw0KGgo="}]
+//	          }
+//	        ]
+//	      }
+//	    ]
+//	  }
+//	}
+//
+// The library holds the fields of the library meta.json file, along with the notebooks. Each notebook holds the
+// fields of its meta.json file, along with its notes. Each note holds the fields of its meta.json and content.json
+// files, along with its resources (omitted when not loaded), whose data is stored as a base64 data URI.
+// The unknown fields of the Quiver files (see the Extra fields) are kept at the same level.
+//
+// Readers should reject bundles with a greater version. Documents without the "format" and "version" fields are
+// read as libraries, as written by older versions of this package.
+
+// The format and version of the bundles written by EncodeBundle.
+const (
+	BundleFormat  = "quiver-bundle"
+	BundleVersion = 1
+)
+
+// bundle is the envelope of a JSON bundle.
+type bundle struct {
+	Format  string   `json:"format"`
+	Version int      `json:"version"`
+	Library *Library `json:"library"`
+}
+
+// EncodeBundle writes the whole library as a JSON bundle.
+// The resources are included when loaded (see ReadOptions.Resources).
+func EncodeBundle(w io.Writer, lib *Library) error {
+	return json.NewEncoder(w).Encode(&bundle{BundleFormat, BundleVersion, lib})
+}
+
+// DecodeBundle reads a library from a JSON bundle, as written by EncodeBundle.
+func DecodeBundle(r io.Reader) (*Library, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var b struct {
+		Format    *string         `json:"format"`
+		Version   int             `json:"version"`
+		Library   json.RawMessage `json:"library"`
+		Notebooks json.RawMessage `json:"notebooks"`
+	}
+	err = json.Unmarshal(data, &b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotABundle, err)
+	}
+	switch {
+	case b.Format == nil && b.Notebooks != nil:
+		// a library, as written by older versions
+	case b.Format == nil || *b.Format != BundleFormat || b.Library == nil:
+		return nil, fmt.Errorf("%w: unknown format", ErrNotABundle)
+	case b.Version < 1 || b.Version > BundleVersion:
+		return nil, fmt.Errorf("%w: unsupported version %v", ErrNotABundle, b.Version)
+	default:
+		data = b.Library
+	}
+
+	lib := new(Library)
+	err = json.Unmarshal(data, lib)
+	if err != nil {
+		return nil, err
+	}
+	return lib, nil
+}
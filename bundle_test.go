@@ -0,0 +1,98 @@
+package quiver_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ushu/quiver"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	t.Parallel()
+	lib, err := quiver.ReadLibrary(fixturePath("Quiver.qvlibrary"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = quiver.EncodeBundle(&buf, lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := buf.String()
+	if !strings.Contains(bundle, `"created_at":1505731210`) {
+		t.Errorf("timestamps should marshal as integers")
+	}
+
+	out, err := quiver.DecodeBundle(strings.NewReader(bundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	err = quiver.EncodeBundle(&buf, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != bundle {
+		t.Errorf("the bundle changed after a round trip:\n%s\nwant:\n%s", buf.String(), bundle)
+	}
+
+	// and the library can be rebuilt
+	outPath := filepath.Join(t.TempDir(), "Out.qvlibrary")
+	err = quiver.WriteLibrary(outPath, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := quiver.ReadLibrary(outPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range lib.Notebooks[0].Notes {
+		rn := restored.NoteByUUID(n.UUID)
+		if rn == nil {
+			t.Fatalf("note %v not restored", n.UUID)
+		}
+		if len(rn.Resources) != len(n.Resources) {
+			t.Fatalf("len(rn.Resources) = %v; want %v", len(rn.Resources), len(n.Resources))
+		}
+		for i, r := range n.Resources {
+			if rn.Resources[i].Name != r.Name || !bytes.Equal(rn.Resources[i].Data, r.Data) {
+				t.Errorf("resource %v of note %v differs", r.Name, n.UUID)
+			}
+		}
+	}
+}
+
+func TestDecodeBundle(t *testing.T) {
+	t.Parallel()
+
+	// older versions wrote the bare library, with URL-safe base64 resources
+	legacy := `{"notebooks": [{"name": "NB", "uuid": "NB", "notes": [{"uuid": "N", "title": "N", "cells": [],
+		"resources": [{"Name": "a.txt", "Data": "data:text/plain,aGk_"}]}]}]}`
+	lib, err := quiver.DecodeBundle(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lib.LibraryMetadata != nil {
+		t.Errorf("lib.LibraryMetadata = %v; want nil", lib.LibraryMetadata)
+	}
+	n := lib.NoteByUUID("N")
+	if n == nil || len(n.Resources) != 1 || string(n.Resources[0].Data) != "hi?" {
+		t.Errorf("the legacy library was not decoded properly")
+	}
+
+	for _, bad := range []string{
+		`[]`,
+		`{"title": "not a library"}`,
+		`{"format": "other", "version": 1, "library": {}}`,
+		`{"format": "quiver-bundle", "version": 99, "library": {}}`,
+	} {
+		_, err := quiver.DecodeBundle(strings.NewReader(bad))
+		if !errors.Is(err, quiver.ErrNotABundle) {
+			t.Errorf("DecodeBundle(%v) = %v; want %v", bad, err, quiver.ErrNotABundle)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package quiver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ProblemKind tells which kind of inconsistency was found by Check.
+type ProblemKind string
+
+// The inconsistencies found by Check
+const (
+	// The directory of a note does not match the UUID of its meta.json file.
+	NoteUUIDMismatch ProblemKind = "note-uuid-mismatch"
+	// Several notes have the same UUID.
+	DuplicateNote ProblemKind = "duplicate-note"
+	// The directory of a notebook does not match the UUID of its meta.json file.
+	NotebookUUIDMismatch ProblemKind = "notebook-uuid-mismatch"
+	// Several notebooks have the same UUID.
+	DuplicateNotebook ProblemKind = "duplicate-notebook"
+	// The hierarchy of the library (LibraryMetadata.Children) references an unknown notebook.
+	MissingNotebook ProblemKind = "missing-notebook"
+	// A notebook (other than the Inbox and the Trash) is missing from the hierarchy of the library.
+	UnlistedNotebook ProblemKind = "unlisted-notebook"
+	// The title of the content.json file of a note differs from the one of its meta.json file.
+	TitleMismatch ProblemKind = "title-mismatch"
+	// A note was updated before being created.
+	InvalidTimestamps ProblemKind = "invalid-timestamps"
+	// A cell has an unknown type.
+	UnknownCellType ProblemKind = "unknown-cell-type"
+)
+
+// ErrNotRepairable is returned when trying to repair a problem that needs a human decision.
+var ErrNotRepairable = errors.New("the problem cannot be repaired automatically")
+
+// Problem is an inconsistency found in a library.
+type Problem struct {
+	// The kind of problem.
+	Kind ProblemKind `json:"kind"`
+	// The path of the faulty element, when known: an OS path when the library was loaded from disk, or a path
+	// in the fs.FS otherwise.
+	Path string `json:"path,omitempty"`
+	// The UUID of the faulty element.
+	UUID string `json:"uuid"`
+	// A human readable description of the problem.
+	Message string `json:"message"`
+
+	// the faulty elements
+	lib      *Library
+	notebook *Notebook
+	note     *Note
+}
+
+// String returns the problem as a tab-separated line: kind, path (or UUID) and message.
+func (p Problem) String() string {
+	where := p.Path
+	if where == "" {
+		where = p.UUID
+	}
+	return fmt.Sprintf("%v\t%v\t%v", p.Kind, where, p.Message)
+}
+
+// Check looks for the inconsistencies of the library, usually left by sync tools or manual edits.
+// The Path of the problems are only known when the library was loaded from disk.
+func Check(lib *Library) []Problem {
+	var problems []Problem
+	add := func(p Problem) {
+		p.lib = lib
+		if p.Path == "" && p.notebook != nil {
+			p.Path = elementPath(p.notebook.fsys, p.notebook.path)
+		}
+		problems = append(problems, p)
+	}
+
+	notebooks := make(map[string]*Notebook, len(lib.Notebooks))
+	notes := make(map[string]*Note)
+	for _, nb := range lib.Notebooks {
+		if nb.NotebookMetadata == nil {
+			continue
+		}
+		if nb.path != "" && path.Base(nb.path) != nb.UUID+".qvnotebook" {
+			add(Problem{
+				Kind: NotebookUUIDMismatch, UUID: nb.UUID, notebook: nb,
+				Message: fmt.Sprintf("the directory should be named %q", nb.UUID+".qvnotebook"),
+			})
+		}
+		if other, ok := notebooks[nb.UUID]; ok {
+			add(Problem{
+				Kind: DuplicateNotebook, UUID: nb.UUID, notebook: nb,
+				Message: fmt.Sprintf("the UUID is also used by %q", elementName(elementPath(other.fsys, other.path), other.Name)),
+			})
+		} else {
+			notebooks[nb.UUID] = nb
+		}
+
+		for _, n := range nb.Notes {
+			if n.NoteMetadata == nil {
+				continue
+			}
+			problems = append(problems, checkNote(lib, nb, n, notes)...)
+		}
+	}
+
+	// the hierarchy
+	listed := make(map[string]bool)
+	if lib.LibraryMetadata != nil {
+		var walk func(children []NotebookHierarchyInfo)
+		walk = func(children []NotebookHierarchyInfo) {
+			for _, c := range children {
+				listed[c.UUID] = true
+				if notebooks[c.UUID] == nil {
+					add(Problem{Kind: MissingNotebook, UUID: c.UUID, Message: "the notebook of the hierarchy does not exist"})
+				}
+				walk(c.Children)
+			}
+		}
+		walk(lib.Children)
+	}
+	for _, nb := range lib.Notebooks {
+		if nb.NotebookMetadata != nil && !listed[nb.UUID] && !nb.IsInbox() && !nb.IsTrash() {
+			add(Problem{Kind: UnlistedNotebook, UUID: nb.UUID, notebook: nb, Message: "the notebook is missing from the hierarchy"})
+			listed[nb.UUID] = true
+		}
+	}
+
+	return problems
+}
+
+func checkNote(lib *Library, nb *Notebook, n *Note, notes map[string]*Note) []Problem {
+	var problems []Problem
+	add := func(p Problem) {
+		p.lib, p.notebook, p.note = lib, nb, n
+		p.UUID, p.Path = n.UUID, elementPath(n.fsys, n.path)
+		problems = append(problems, p)
+	}
+
+	if n.path != "" && path.Base(n.path) != n.UUID+".qvnote" {
+		add(Problem{Kind: NoteUUIDMismatch, Message: fmt.Sprintf("the directory should be named %q", n.UUID+".qvnote")})
+	}
+	if other, ok := notes[n.UUID]; ok {
+		add(Problem{Kind: DuplicateNote, Message: fmt.Sprintf("the UUID is also used by %q", elementName(elementPath(other.fsys, other.path), other.Title))})
+	} else {
+		notes[n.UUID] = n
+	}
+
+	if n.NoteContent != nil {
+		var title string
+		if raw, ok := n.NoteContent.Extra["title"]; ok && json.Unmarshal(raw, &title) == nil && title != n.Title {
+			add(Problem{Kind: TitleMismatch, Message: fmt.Sprintf("the content title is %q instead of %q", title, n.Title)})
+		}
+		for i, c := range n.Cells {
+			switch c.Type {
+			case CodeCell, TextCell, MarkdownCell, LatexCell, DiagramCell:
+			default:
+				add(Problem{Kind: UnknownCellType, Message: fmt.Sprintf("cell %v has an unknown type %q", i, c.Type)})
+			}
+		}
+	}
+
+	if time.Time(n.UpdatedAt).Before(time.Time(n.CreatedAt)) {
+		add(Problem{Kind: InvalidTimestamps, Message: "the note was updated before being created"})
+	}
+
+	return problems
+}
+
+func elementName(path, name string) string {
+	if path != "" {
+		return path
+	}
+	return name
+}
+
+// Repair fixes the problem on disk, and updates the loaded library accordingly:
+//   - misnamed directories are renamed after their UUID, unless the target already exists,
+//   - the hierarchy of the library drops the missing notebooks, and lists the unlisted ones at its root,
+//   - the title of content.json is replaced with the one of meta.json,
+//   - the update time of the note is set to its creation time, when earlier.
+//
+// Duplicates and unknown cell types need a human decision: ErrNotRepairable is returned.
+// The library should have been loaded from an OS path, otherwise ErrReadOnly is returned.
+func (p Problem) Repair() error {
+	// nothing is changed in memory when the library cannot be modified
+	fsys := p.lib.fsys
+	if p.note != nil {
+		fsys = p.note.fsys
+	} else if p.notebook != nil {
+		fsys = p.notebook.fsys
+	}
+	if _, err := osPath(fsys, ""); err != nil {
+		return err
+	}
+
+	switch p.Kind {
+	case NoteUUIDMismatch:
+		dir, err := rename(p.note.fsys, p.note.path, p.UUID+".qvnote")
+		if err != nil {
+			return err
+		}
+		p.note.moved(dir)
+	case NotebookUUIDMismatch:
+		dir, err := rename(p.notebook.fsys, p.notebook.path, p.UUID+".qvnotebook")
+		if err != nil {
+			return err
+		}
+		p.notebook.moved(dir)
+	case MissingNotebook:
+		p.lib.LibraryMetadata.Children = removeHierarchy(p.lib.LibraryMetadata.Children, p.UUID)
+		return p.lib.SaveMetadata()
+	case UnlistedNotebook:
+		if p.lib.LibraryMetadata == nil {
+			p.lib.LibraryMetadata = &LibraryMetadata{}
+		}
+		p.lib.Children = append(p.lib.Children, NotebookHierarchyInfo{UUID: p.UUID, Children: []NotebookHierarchyInfo{}})
+		return p.lib.SaveMetadata()
+	case TitleMismatch:
+		delete(p.note.NoteContent.Extra, "title")
+		return p.note.SaveContent()
+	case InvalidTimestamps:
+		p.note.UpdatedAt = p.note.CreatedAt
+		return p.note.SaveMetadata()
+	default:
+		return ErrNotRepairable
+	}
+	return nil
+}
+
+// SaveMetadata saves the metadata of the library back into its "meta.json" file.
+// The library should have been loaded from an OS path, otherwise ErrReadOnly is returned.
+func (m *Library) SaveMetadata() error {
+	p, err := osPath(m.fsys, path.Join(m.path, "meta.json"))
+	if err != nil {
+		return err
+	}
+	return WriteLibraryMetadata(p, m.LibraryMetadata)
+}
+
+// rename renames the element at the given path in fsys, and returns its new path.
+func rename(fsys fs.FS, name, newBase string) (string, error) {
+	ofs, ok := fsys.(osDirFS)
+	if !ok || name == "" {
+		return "", ErrReadOnly
+	}
+	newName := path.Join(path.Dir(name), newBase)
+	from, _ := osPath(ofs, name)
+	to, _ := osPath(ofs, newName)
+	if _, err := os.Stat(to); err == nil {
+		return "", fmt.Errorf("cannot rename %q: %q already exists", name, newName)
+	}
+	return newName, os.Rename(from, to)
+}
+
+// moved updates the paths of the notebook, and of its notes, after its directory was renamed.
+func (n *Notebook) moved(dir string) {
+	old := n.path
+	n.path = dir
+	for _, note := range n.Notes {
+		note.moved(dir + strings.TrimPrefix(note.path, old))
+	}
+}
+
+// moved updates the paths of the note, and of its resources, after its directory was renamed.
+func (n *Note) moved(dir string) {
+	old := n.path
+	n.path = dir
+	for _, r := range n.Resources {
+		if r.path != "" {
+			r.path = dir + strings.TrimPrefix(r.path, old)
+		}
+	}
+}
+
+// removeHierarchy removes the notebook with the given UUID from the hierarchy: its children take its place.
+func removeHierarchy(children []NotebookHierarchyInfo, uuid string) []NotebookHierarchyInfo {
+	out := make([]NotebookHierarchyInfo, 0, len(children))
+	for _, c := range children {
+		c.Children = removeHierarchy(c.Children, uuid)
+		if c.UUID == uuid {
+			out = append(out, c.Children...)
+		} else {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package quiver_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ushu/quiver"
+)
+
+// editJSON updates the JSON object stored in the file.
+func editJSON(t *testing.T, p string, f func(m map[string]interface{})) {
+	t.Helper()
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f(m)
+	data, err = json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(p, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func problemKinds(problems []quiver.Problem) string {
+	kinds := make([]string, len(problems))
+	for i, p := range problems {
+		kinds[i] = string(p.Kind)
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, " ")
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	libPath := copyFixture(t, "Quiver.qvlibrary")
+	nbPath := filepath.Join(libPath, "Quiver Test.qvnotebook")
+
+	// break the library
+	err := ioutil.WriteFile(filepath.Join(libPath, "meta.json"), []byte(`{"children": [{"uuid": "GONE", "children": []}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Rename(
+		filepath.Join(nbPath, "D2A1CC36-CC97-4701-A895-EFC98EF47026.qvnote"),
+		filepath.Join(nbPath, "D2A1CC36 (conflicted copy).qvnote"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editJSON(t, filepath.Join(nbPath, "73385592-0CAB-41E5-9045-AEC528C2915A.qvnote", "meta.json"), func(m map[string]interface{}) {
+		m["updated_at"] = 1
+	})
+	editJSON(t, filepath.Join(nbPath, "B59AC519-2A2C-4EC8-B701-E69F54F40A85.qvnote", "content.json"), func(m map[string]interface{}) {
+		m["title"] = "Old title"
+		m["cells"] = append(m["cells"].([]interface{}), map[string]interface{}{"type": "video", "data": ""})
+	})
+
+	lib, err := quiver.ReadLibrary(libPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := quiver.Check(lib)
+	want := "invalid-timestamps missing-notebook note-uuid-mismatch notebook-uuid-mismatch title-mismatch unknown-cell-type unlisted-notebook"
+	if got := problemKinds(problems); got != want {
+		t.Fatalf("Check() = %v; want %v", got, want)
+	}
+
+	// repair what can be
+	for _, p := range problems {
+		err := p.Repair()
+		if p.Kind == quiver.UnknownCellType {
+			if !errors.Is(err, quiver.ErrNotRepairable) {
+				t.Errorf("p.Repair() = %v; want %v", err, quiver.ErrNotRepairable)
+			}
+		} else if err != nil {
+			t.Errorf("%v: p.Repair() failed: %v", p.Kind, err)
+		}
+	}
+
+	// and check again
+	lib, err = quiver.ReadLibrary(libPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := problemKinds(quiver.Check(lib)); got != "unknown-cell-type" {
+		t.Errorf("Check() = %v after repair; want %v", got, "unknown-cell-type")
+	}
+	if lib.NotebookByUUID("FIXTURE") == nil || len(lib.Children) != 1 || lib.Children[0].UUID != "FIXTURE" {
+		t.Errorf("the hierarchy was not repaired: %v", lib.Children)
+	}
+	if n := lib.NoteByUUID("B59AC519-2A2C-4EC8-B701-E69F54F40A85"); n == nil || len(n.Resources) != 2 {
+		t.Errorf("the resources were lost")
+	}
+}
+
+func TestCheckReadOnly(t *testing.T) {
+	t.Parallel()
+
+	lib, err := quiver.ReadLibraryFS(specialLibrary, "Special.qvlibrary", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := quiver.Check(lib)
+	if got := problemKinds(problems); got != "missing-notebook" {
+		t.Fatalf("Check() = %v; want %v", got, "missing-notebook")
+	}
+	if got := problems[0].String(); got != "missing-notebook\tGONE\tthe notebook of the hierarchy does not exist" {
+		t.Errorf("problems[0].String() = %q", got)
+	}
+	if err := problems[0].Repair(); !errors.Is(err, quiver.ErrReadOnly) {
+		t.Errorf("problems[0].Repair() = %v; want %v", err, quiver.ErrReadOnly)
+	}
+}
@@ -0,0 +1,49 @@
+/*
+The quiver tool gathers several commands to work with Quiver libraries.
+
+Usage:
+
+	$ quiver COMMAND [OPTIONS] ARGS...
+
+	# To list the notebooks and notes of a library
+	$ quiver ls /path/to/Quiver.qvlibrary
+
+	# To print a note, by UUID or title
+	$ quiver show /path/to/Quiver.qvlibrary 'My note'
+
+	# To search the notes of a library
+	$ quiver search /path/to/Quiver.qvlibrary 'goroutine tag:golang type:code'
+
+	# To print statistics about the notes tagged "golang", as JSON
+	$ quiver stats -tag golang -format json /path/to/Quiver.qvlibrary
+
+	# To check the consistency of a library, and repair it
+	$ quiver check -repair /path/to/Quiver.qvlibrary
+
+	# To export a library as a JSON bundle, or as Markdown files
+	$ quiver export json /path/to/Quiver.qvlibrary quiver.json
+	$ quiver export markdown /path/to/Quiver.qvlibrary /output/path
+
+	# To review the changes between two copies of a library
+	$ quiver diff /path/to/Old.qvlibrary /path/to/New.qvlibrary
+
+	# To merge two copies of a library which diverged from a common one
+	$ quiver merge Base.qvlibrary Ours.qvlibrary Theirs.qvlibrary Merged.qvlibrary
+
+The commands working on a single library share the same options, like -library, -include-trash, -notebook, -tag and
+-format. The exit status is 0 on success, 1 when something was found (like problems, differences or conflicts), and 2
+on errors.
+
+Run "quiver help COMMAND" for the details of each command.
+*/
+package main
+
+import (
+	"os"
+
+	"github.com/ushu/quiver/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(os.Args[1:]))
+}
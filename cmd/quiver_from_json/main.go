@@ -0,0 +1,68 @@
+/*
+The quiver_from_json tool rebuilds a Quiver library from a JSON bundle, as written by the quiver_to_json tool.
+
+Usage:
+
+	# To restore a library
+	$ quiver_from_json quiver.json /path/to/Quiver.qvlibrary
+
+	# To read the bundle from the standard input
+	$ quiver_to_json -res /path/to/Quiver.qvlibrary | quiver_from_json - /path/to/Copy.qvlibrary
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ushu/quiver"
+)
+
+// Tells the tool to write into an existing library.
+var flagForce bool
+
+func init() {
+	flag.BoolVar(&flagForce, "f", false, "overwrite the notes of an existing library")
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		fmt.Println("Usage: quiver_from_json [-f] JSON_BUNDLE QUIVER_LIBRARY")
+		fmt.Println()
+		fmt.Println("Options:")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	inPath, outPath := flag.Arg(0), flag.Arg(1)
+	if _, err := os.Stat(outPath); err == nil && !flagForce {
+		fmt.Printf("%v already exists, use -f to overwrite it\n", outPath)
+		os.Exit(1)
+	}
+
+	var in io.Reader = os.Stdin
+	if inPath != "-" {
+		f, err := os.Open(inPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	library, err := quiver.DecodeBundle(in)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	err = quiver.WriteLibrary(outPath, library)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+The quiver_resources tool reports, for each note of a Quiver library, the resources referenced by the cells that
+are missing on disk, and the resources stored on disk that no cell references.
+
+Usage:
+
+	# To list missing and orphaned resources
+	$ quiver_resources /path/to/Quiver.qvlibrary
+
+	# To list the orphaned resources that would be deleted
+	$ quiver_resources -prune /path/to/Quiver.qvlibrary
+
+	# To actually delete the orphaned resources
+	$ quiver_resources -prune -yes /path/to/Quiver.qvlibrary
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ushu/quiver"
+)
+
+// Tells the tool to prune the orphaned resources (only a dry run, unless flagYes is set).
+var flagPrune bool
+
+// Confirms that the orphaned resources should actually be deleted.
+var flagYes bool
+
+func init() {
+	flag.BoolVar(&flagPrune, "prune", false, "list the orphaned resources to delete (dry run, see -yes)")
+	flag.BoolVar(&flagYes, "yes", false, "with -prune, actually delete the orphaned resources")
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Println("Usage: quiver_resources [-prune [-yes]] QUIVER_LIBRARY")
+		fmt.Println()
+		fmt.Println("Options:")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	// Only list the resources: we don't need their data
+	opts := &quiver.ReadOptions{Resources: quiver.LazyResources}
+	library, err := quiver.ReadLibraryContext(context.Background(), flag.Arg(0), opts)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var missing, orphaned int
+	var orphanedSize int64
+	for _, r := range library.CheckResources() {
+		nb := library.NotebookOf(r.Note)
+		fmt.Printf("%v / %v (%v)\n", nb.Name, r.Note.Title, r.Note.UUID)
+		for _, name := range r.Missing {
+			fmt.Printf("  missing:  %v\n", name)
+		}
+		for _, res := range r.Orphaned {
+			fmt.Printf("  orphaned: %v (%v bytes)\n", res.Name, res.Size)
+			orphanedSize += res.Size
+		}
+		missing += len(r.Missing)
+		orphaned += len(r.Orphaned)
+
+		if flagPrune && flagYes {
+			err = r.PruneOrphans()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	fmt.Printf("%v missing, %v orphaned resources (%v bytes)\n", missing, orphaned, orphanedSize)
+	if flagPrune && orphaned > 0 {
+		if flagYes {
+			fmt.Printf("Pruned %v orphaned resources\n", orphaned)
+		} else {
+			fmt.Printf("Would prune %v orphaned resources: run again with -prune -yes to delete them\n", orphaned)
+		}
+	}
+}
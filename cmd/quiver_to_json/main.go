@@ -1,5 +1,9 @@
 /*
-The quiver_to_json tool loads a provided Quiver library into a single JSON.
+The quiver_to_json tool loads a provided Quiver library into a single JSON bundle, that can be turned back into a
+library with the quiver_from_json tool (see quiver.EncodeBundle for the format).
+
+It is a shortcut for "quiver export json", kept for compatibility: unlike the quiver tool, the Trash is included by
+default.
 
 Usage:
 
@@ -8,48 +12,62 @@ Usage:
 
 	# To include the content of all resources as data URIs
 	$ quiver_to_json -res /path/to/Quiver.qvlibrary > quiver.json
+
+	# To skip malformed notes and junk files instead of failing
+	$ quiver_to_json -lenient /path/to/Quiver.qvlibrary > quiver.json
 */
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 
-	"flag"
-
-	"github.com/ushu/quiver"
+	"github.com/ushu/quiver/internal/cli"
 )
 
 // Tells the tool to also load the resources.
 var flagRes bool
 
+// Tells the tool to skip malformed notes instead of failing.
+var flagLenient bool
+
+// Tells the tool to skip the notes in the Trash.
+var flagNoTrash bool
+
 func init() {
 	flag.BoolVar(&flagRes, "res", false, "load resources in JSON")
+	flag.BoolVar(&flagLenient, "lenient", false, "skip malformed notes and junk files")
+	flag.BoolVar(&flagNoTrash, "notrash", false, "skip the notes in the Trash")
 }
 
 func main() {
 	flag.Parse()
 
 	if flag.NArg() != 1 {
-		fmt.Println("Usage: quiver_to_json [-res] QUIVER_LIBRARY")
+		fmt.Println("Usage: quiver_to_json [-res] [-lenient] [-notrash] QUIVER_LIBRARY")
 		fmt.Println()
 		fmt.Println("Options:")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	// Read full library into memory
-	library, err := quiver.ReadLibrary(flag.Args()[0], flagRes)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	// the options of "quiver export json"
+	var args []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{flagRes, "-res"},
+		{flagLenient, "-lenient"},
+		{!flagNoTrash, "-include-trash"},
+	} {
+		if f.set {
+			args = append(args, f.name)
+		}
 	}
-
-	// Outputs the library as JSON
-	err = json.NewEncoder(os.Stdout).Encode(library)
-	if err != nil {
-		fmt.Println(err)
+	// (the wrappers keep exiting with 1 on errors, like before the quiver tool)
+	if cli.Run("export json", append(args, flag.Arg(0))) != cli.ExitOK {
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,131 @@
+package quiver
+
+import (
+	"context"
+	"io/fs"
+	"runtime"
+	"sync"
+)
+
+// ReadLibraryContext loads the Quiver library at the given path, as configured by opts.
+//
+// Notes are parsed in parallel by a pool of opts.Concurrency workers, and loading stops as soon as ctx is cancelled.
+// The notebooks and notes are returned in the same order as ReadLibrary.
+func ReadLibraryContext(ctx context.Context, path string, opts *ReadOptions) (*Library, error) {
+	fsys, root := dirFS(path)
+	return ReadLibraryFSContext(ctx, fsys, root, opts)
+}
+
+// ReadLibraryFSContext loads the Quiver library found at the given root in fsys, as configured by opts.
+// See ReadLibraryContext for details.
+func ReadLibraryFSContext(ctx context.Context, fsys fs.FS, root string, opts *ReadOptions) (*Library, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// all the notes are sent to the workers, each one with the slot where it will be stored
+	type job struct {
+		path string
+		note **Note
+	}
+	jobs := make(chan job)
+
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	w := newWarnings(fsys, opts)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				// keep draining the jobs once cancelled
+				if ctx.Err() != nil {
+					continue
+				}
+				n, err := ReadNoteFSWithOptions(fsys, j.path, opts)
+				if err != nil {
+					if !w.skip(j.path, err) {
+						fail(err)
+					}
+					continue
+				}
+				*j.note = n
+			}
+		}()
+	}
+
+	// walk the library, and send the notes to the workers as soon as they are found
+	lib, err := func() (*Library, error) {
+		defer close(jobs)
+
+		metadata, paths, err := readLibraryDir(fsys, root, opts, w)
+		if err != nil {
+			return nil, err
+		}
+
+		notebooks := make([]*Notebook, 0, len(paths))
+		for _, p := range paths {
+			nbm, notePaths, err := readNotebookDir(fsys, p, w)
+			if err != nil {
+				if w.skip(p, err) {
+					continue
+				}
+				return nil, err
+			}
+			nb := &Notebook{NotebookMetadata: nbm, Notes: make([]*Note, len(notePaths)), fsys: fsys, path: p}
+			notebooks = append(notebooks, nb)
+
+			for j, np := range notePaths {
+				select {
+				case jobs <- job{np, &nb.Notes[j]}:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+		}
+
+		return &Library{LibraryMetadata: metadata, Notebooks: notebooks, fsys: fsys, path: root}, nil
+	}()
+	wg.Wait()
+
+	// errors from the workers come first, since they cancel the walk
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// remove the notes skipped in lenient mode
+	for _, nb := range lib.Notebooks {
+		notes := nb.Notes[:0]
+		for _, n := range nb.Notes {
+			if n != nil {
+				notes = append(notes, n)
+			}
+		}
+		nb.Notes = notes
+	}
+	lib.Warnings = w.all()
+	lib.Reindex()
+
+	return lib, nil
+}
+
+func (o *ReadOptions) concurrency() int {
+	if o == nil || o.Concurrency <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.Concurrency
+}
@@ -0,0 +1,65 @@
+package quiver_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ushu/quiver"
+)
+
+func TestReadLibraryContext(t *testing.T) {
+	t.Parallel()
+	libPath := fixturePath("Quiver.qvlibrary")
+
+	want, err := quiver.ReadLibrary(libPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, concurrency := range []int{0, 1, 2, 8} {
+		opts := &quiver.ReadOptions{Concurrency: concurrency}
+		lib, err := quiver.ReadLibraryContext(context.Background(), libPath, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The ordering should be the same as the sequential one
+		if len(lib.Notebooks) != len(want.Notebooks) {
+			t.Fatalf("len(lib.Notebooks) = %v; want %v", len(lib.Notebooks), len(want.Notebooks))
+		}
+		for i, nb := range want.Notebooks {
+			if lib.Notebooks[i].UUID != nb.UUID {
+				t.Errorf("lib.Notebooks[%v].UUID = %q; want %q", i, lib.Notebooks[i].UUID, nb.UUID)
+			}
+			if len(lib.Notebooks[i].Notes) != len(nb.Notes) {
+				t.Fatalf("len(lib.Notebooks[%v].Notes) = %v; want %v", i, len(lib.Notebooks[i].Notes), len(nb.Notes))
+			}
+			for j, n := range nb.Notes {
+				if lib.Notebooks[i].Notes[j].UUID != n.UUID {
+					t.Errorf("lib.Notebooks[%v].Notes[%v].UUID = %q; want %q", i, j, lib.Notebooks[i].Notes[j].UUID, n.UUID)
+				}
+			}
+		}
+	}
+}
+
+func TestReadLibraryContextCancelled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := quiver.ReadLibraryContext(ctx, fixturePath("Quiver.qvlibrary"), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestReadLibraryContextError(t *testing.T) {
+	t.Parallel()
+
+	_, err := quiver.ReadLibraryContext(context.Background(), fixturePath("Missing.qvlibrary"), nil)
+	if err == nil {
+		t.Error("loading a missing library should fail")
+	}
+}
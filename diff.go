@@ -0,0 +1,361 @@
+package quiver
+
+import (
+	"sort"
+	"strings"
+)
+
+// LibraryDiff holds the changes between two versions of a library.
+// Notebooks and notes are matched by UUID, wherever they are stored.
+type LibraryDiff struct {
+	// The added, removed, renamed and moved notebooks.
+	Notebooks []*NotebookDiff
+	// The added, removed and modified notes.
+	Notes []*NoteDiff
+}
+
+// Empty returns true when the two libraries hold the same notebooks and notes.
+func (d *LibraryDiff) Empty() bool {
+	return len(d.Notebooks) == 0 && len(d.Notes) == 0
+}
+
+// NotebookDiff holds the changes of a notebook.
+type NotebookDiff struct {
+	// The UUID of the notebook.
+	UUID string
+	// The old and new versions of the notebook: Old is nil for added notebooks, and New for removed ones.
+	Old, New *Notebook
+	// The name of the notebook changed.
+	Renamed bool
+	// The parent of the notebook in the hierarchy changed: their UUIDs, or "" for the root.
+	Moved                bool
+	OldParent, NewParent string
+}
+
+// Added returns true when the notebook was added.
+func (d *NotebookDiff) Added() bool {
+	return d.Old == nil
+}
+
+// Removed returns true when the notebook was removed.
+func (d *NotebookDiff) Removed() bool {
+	return d.New == nil
+}
+
+// NoteDiff holds the changes of a note.
+type NoteDiff struct {
+	// The UUID of the note.
+	UUID string
+	// The old and new versions of the note: Old is nil for added notes, and New for removed ones.
+	Old, New *Note
+	// The notebooks holding the old and new versions of the note.
+	OldNotebook, NewNotebook *Notebook
+	// The title of the note changed.
+	Renamed bool
+	// The note moved to another notebook.
+	Moved bool
+	// The tags added to and removed from the note.
+	AddedTags, RemovedTags []string
+	// The inserted, deleted and modified cells.
+	Cells []*CellDiff
+}
+
+// Added returns true when the note was added.
+func (d *NoteDiff) Added() bool {
+	return d.Old == nil
+}
+
+// Removed returns true when the note was removed.
+func (d *NoteDiff) Removed() bool {
+	return d.New == nil
+}
+
+func (d *NoteDiff) empty() bool {
+	return !d.Renamed && !d.Moved && len(d.AddedTags) == 0 && len(d.RemovedTags) == 0 && len(d.Cells) == 0
+}
+
+// CellChange tells how a cell changed.
+type CellChange string
+
+// The changes of cells
+const (
+	CellInserted CellChange = "inserted"
+	CellDeleted  CellChange = "deleted"
+	CellModified CellChange = "modified"
+)
+
+// CellDiff holds the changes of a cell.
+type CellDiff struct {
+	// How the cell changed.
+	Change CellChange
+	// The positions of the cell in the old and new notes, or -1 for inserted and deleted cells.
+	OldIndex, NewIndex int
+	// The old and new versions of the cell: Old is nil for inserted cells, and New for deleted ones.
+	Old, New *Cell
+	// The line by line diff of the Data of the cell.
+	Lines []LineDiff
+}
+
+// LineOp tells how a line changed.
+type LineOp byte
+
+// The changes of lines, as displayed in unified diffs
+const (
+	LineKept     LineOp = ' '
+	LineInserted LineOp = '+'
+	LineDeleted  LineOp = '-'
+)
+
+// LineDiff is a line of a text diff.
+type LineDiff struct {
+	Op   LineOp
+	Text string
+}
+
+// String returns the line as in unified diffs: "+inserted", "-deleted" or " kept".
+func (l LineDiff) String() string {
+	return string(l.Op) + l.Text
+}
+
+// Diff computes the changes between the a and b versions of a library.
+func Diff(a, b *Library) *LibraryDiff {
+	d := new(LibraryDiff)
+
+	// notebooks
+	parentsA, parentsB := hierarchyParents(a), hierarchyParents(b)
+	for _, nb := range b.Notebooks {
+		if nb.NotebookMetadata == nil {
+			continue
+		}
+		old := a.NotebookByUUID(nb.UUID)
+		if old == nil {
+			d.Notebooks = append(d.Notebooks, &NotebookDiff{UUID: nb.UUID, New: nb, NewParent: parentsB[nb.UUID]})
+			continue
+		}
+		nd := &NotebookDiff{
+			UUID: nb.UUID, Old: old, New: nb,
+			Renamed:   old.Name != nb.Name,
+			OldParent: parentsA[nb.UUID], NewParent: parentsB[nb.UUID],
+		}
+		nd.Moved = parentsA != nil && parentsB != nil && nd.OldParent != nd.NewParent
+		if nd.Renamed || nd.Moved {
+			d.Notebooks = append(d.Notebooks, nd)
+		}
+	}
+	for _, nb := range a.Notebooks {
+		if nb.NotebookMetadata != nil && b.NotebookByUUID(nb.UUID) == nil {
+			d.Notebooks = append(d.Notebooks, &NotebookDiff{UUID: nb.UUID, Old: nb, OldParent: parentsA[nb.UUID]})
+		}
+	}
+
+	// notes
+	for _, nb := range b.Notebooks {
+		for _, n := range nb.Notes {
+			if n.NoteMetadata == nil {
+				continue
+			}
+			old := a.NoteByUUID(n.UUID)
+			if old == nil {
+				d.Notes = append(d.Notes, &NoteDiff{UUID: n.UUID, New: n, NewNotebook: nb})
+				continue
+			}
+			nd := DiffNotes(old, n)
+			nd.OldNotebook, nd.NewNotebook = a.NotebookOf(old), nb
+			nd.Moved = nd.OldNotebook != nil && nd.OldNotebook.NotebookMetadata != nil && nb.NotebookMetadata != nil &&
+				nd.OldNotebook.UUID != nb.UUID
+			if !nd.empty() {
+				d.Notes = append(d.Notes, nd)
+			}
+		}
+	}
+	for _, nb := range a.Notebooks {
+		for _, n := range nb.Notes {
+			if n.NoteMetadata != nil && b.NoteByUUID(n.UUID) == nil {
+				d.Notes = append(d.Notes, &NoteDiff{UUID: n.UUID, Old: n, OldNotebook: nb})
+			}
+		}
+	}
+
+	return d
+}
+
+// DiffNotes computes the changes between the a and b versions of a note: its title, tags and cells.
+// The notebooks of the notes are left unknown.
+func DiffNotes(a, b *Note) *NoteDiff {
+	d := &NoteDiff{UUID: b.UUID, Old: a, New: b, Renamed: a.Title != b.Title}
+
+	oldTags, newTags := stringSet(a.Tags), stringSet(b.Tags)
+	for _, t := range b.Tags {
+		if !oldTags[t] {
+			d.AddedTags = append(d.AddedTags, t)
+		}
+	}
+	for _, t := range a.Tags {
+		if !newTags[t] {
+			d.RemovedTags = append(d.RemovedTags, t)
+		}
+	}
+	sort.Strings(d.AddedTags)
+	sort.Strings(d.RemovedTags)
+
+	d.Cells = diffCells(noteCells(a), noteCells(b))
+	return d
+}
+
+// diffCells matches the identical cells, and pairs the remaining ones as modified cells when possible.
+func diffCells(a, b []*Cell) []*CellDiff {
+	var diffs []*CellDiff
+	// the unmatched cells between two matches
+	flush := func(ai, aj, bi, bj int) {
+		for ai < aj && bi < bj {
+			diffs = append(diffs, &CellDiff{
+				Change: CellModified, OldIndex: ai, NewIndex: bi, Old: a[ai], New: b[bi],
+				Lines: DiffLines(a[ai].Data, b[bi].Data),
+			})
+			ai++
+			bi++
+		}
+		for ; ai < aj; ai++ {
+			diffs = append(diffs, &CellDiff{
+				Change: CellDeleted, OldIndex: ai, NewIndex: -1, Old: a[ai], Lines: DiffLines(a[ai].Data, ""),
+			})
+		}
+		for ; bi < bj; bi++ {
+			diffs = append(diffs, &CellDiff{
+				Change: CellInserted, OldIndex: -1, NewIndex: bi, New: b[bi], Lines: DiffLines("", b[bi].Data),
+			})
+		}
+	}
+
+	ai, bi := 0, 0
+	for _, m := range lcs(len(a), len(b), func(i, j int) bool { return cellsEqual(a[i], b[j]) }) {
+		flush(ai, m[0], bi, m[1])
+		ai, bi = m[0]+1, m[1]+1
+	}
+	flush(ai, len(a), bi, len(b))
+	return diffs
+}
+
+// DiffLines computes the line by line diff of the two texts.
+func DiffLines(a, b string) []LineDiff {
+	la, lb := splitLines(a), splitLines(b)
+	var diffs []LineDiff
+	ai, bi := 0, 0
+	for _, m := range lcs(len(la), len(lb), func(i, j int) bool { return la[i] == lb[j] }) {
+		for ; ai < m[0]; ai++ {
+			diffs = append(diffs, LineDiff{LineDeleted, la[ai]})
+		}
+		for ; bi < m[1]; bi++ {
+			diffs = append(diffs, LineDiff{LineInserted, lb[bi]})
+		}
+		diffs = append(diffs, LineDiff{LineKept, la[ai]})
+		ai, bi = ai+1, bi+1
+	}
+	for ; ai < len(la); ai++ {
+		diffs = append(diffs, LineDiff{LineDeleted, la[ai]})
+	}
+	for ; bi < len(lb); bi++ {
+		diffs = append(diffs, LineDiff{LineInserted, lb[bi]})
+	}
+	return diffs
+}
+
+// maxLCSSize caps the size of the table used by lcs: above it, the parts left between the common prefix and suffix
+// are not matched at all (and the elements are all reported as replaced).
+const maxLCSSize = 1 << 22
+
+// lcs returns the index pairs of a longest common subsequence of two sequences of lengths n and m.
+func lcs(n, m int, equal func(i, j int) bool) [][2]int {
+	// the common prefix and suffix are matched right away
+	var prefix [][2]int
+	for len(prefix) < n && len(prefix) < m && equal(len(prefix), len(prefix)) {
+		prefix = append(prefix, [2]int{len(prefix), len(prefix)})
+	}
+	start := len(prefix)
+	var suffix [][2]int
+	for n-len(suffix) > start && m-len(suffix) > start && equal(n-len(suffix)-1, m-len(suffix)-1) {
+		suffix = append(suffix, [2]int{n - len(suffix) - 1, m - len(suffix) - 1})
+	}
+	for i, j := 0, len(suffix)-1; i < j; i, j = i+1, j-1 {
+		suffix[i], suffix[j] = suffix[j], suffix[i]
+	}
+	n, m = n-len(suffix)-start, m-len(suffix)-start
+	if n*m > maxLCSSize {
+		return append(prefix, suffix...)
+	}
+
+	// dynamic programming over the remaining parts
+	lengths := make([][]int, n+1)
+	for i := range lengths {
+		lengths[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if equal(start+i, start+j) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	pairs := prefix
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case equal(start+i, start+j):
+			pairs = append(pairs, [2]int{start + i, start + j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return append(pairs, suffix...)
+}
+
+// hierarchyParents maps the UUIDs of the notebooks to the ones of their parents ("" at the root), or returns nil
+// when the hierarchy is unknown.
+func hierarchyParents(lib *Library) map[string]string {
+	if lib.LibraryMetadata == nil {
+		return nil
+	}
+	parents := make(map[string]string)
+	var walk func(parent string, children []NotebookHierarchyInfo)
+	walk = func(parent string, children []NotebookHierarchyInfo) {
+		for _, c := range children {
+			parents[c.UUID] = parent
+			walk(c.UUID, c.Children)
+		}
+	}
+	walk("", lib.Children)
+	return parents
+}
+
+func noteCells(n *Note) []*Cell {
+	if n.NoteContent == nil {
+		return nil
+	}
+	return n.Cells
+}
+
+func cellsEqual(a, b *Cell) bool {
+	return a.Type == b.Type && a.Language == b.Language && a.DiagramType == b.DiagramType && a.Data == b.Data
+}
+
+func stringSet(l []string) map[string]bool {
+	s := make(map[string]bool, len(l))
+	for _, e := range l {
+		s[e] = true
+	}
+	return s
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,169 @@
+package quiver_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ushu/quiver"
+)
+
+var diffOld = fstest.MapFS{
+	"A.qvlibrary/meta.json":                          {Data: []byte(`{"children": [{"uuid": "NB1", "children": [{"uuid": "NB2"}]}, {"uuid": "GONE"}]}`)},
+	"A.qvlibrary/NB1.qvnotebook/meta.json":           {Data: []byte(`{"name": "One", "uuid": "NB1"}`)},
+	"A.qvlibrary/NB2.qvnotebook/meta.json":           {Data: []byte(`{"name": "Two", "uuid": "NB2"}`)},
+	"A.qvlibrary/GONE.qvnotebook/meta.json":          {Data: []byte(`{"name": "Gone", "uuid": "GONE"}`)},
+	"A.qvlibrary/NB1.qvnotebook/N1.qvnote/meta.json": {Data: []byte(`{"title": "Note", "uuid": "N1", "tags": ["go", "old"]}`)},
+	"A.qvlibrary/NB1.qvnotebook/N1.qvnote/content.json": {Data: []byte(`{"cells": [
+		{"type": "text", "data": "Same"},
+		{"type": "code", "language": "go", "data": "package main\nfunc main() {\n}"},
+		{"type": "markdown", "data": "Deleted"}
+	]}`)},
+	"A.qvlibrary/NB1.qvnotebook/N2.qvnote/meta.json":    {Data: []byte(`{"title": "Mover", "uuid": "N2"}`)},
+	"A.qvlibrary/NB1.qvnotebook/N2.qvnote/content.json": {Data: []byte(`{"cells": []}`)},
+	"A.qvlibrary/NB1.qvnotebook/N3.qvnote/meta.json":    {Data: []byte(`{"title": "Removed", "uuid": "N3"}`)},
+	"A.qvlibrary/NB1.qvnotebook/N3.qvnote/content.json": {Data: []byte(`{"cells": []}`)},
+}
+
+var diffNew = fstest.MapFS{
+	"B.qvlibrary/meta.json":                          {Data: []byte(`{"children": [{"uuid": "NB1"}, {"uuid": "NB2"}, {"uuid": "NEW"}]}`)},
+	"B.qvlibrary/NB1.qvnotebook/meta.json":           {Data: []byte(`{"name": "One (renamed)", "uuid": "NB1"}`)},
+	"B.qvlibrary/NB2.qvnotebook/meta.json":           {Data: []byte(`{"name": "Two", "uuid": "NB2"}`)},
+	"B.qvlibrary/NEW.qvnotebook/meta.json":           {Data: []byte(`{"name": "New", "uuid": "NEW"}`)},
+	"B.qvlibrary/NB1.qvnotebook/N1.qvnote/meta.json": {Data: []byte(`{"title": "Note v2", "uuid": "N1", "tags": ["go", "new"]}`)},
+	"B.qvlibrary/NB1.qvnotebook/N1.qvnote/content.json": {Data: []byte(`{"cells": [
+		{"type": "text", "data": "Inserted"},
+		{"type": "text", "data": "Same"},
+		{"type": "code", "language": "go", "data": "package main\nfunc main() {\n\tprintln()\n}"}
+	]}`)},
+	"B.qvlibrary/NB2.qvnotebook/N2.qvnote/meta.json":    {Data: []byte(`{"title": "Mover", "uuid": "N2"}`)},
+	"B.qvlibrary/NB2.qvnotebook/N2.qvnote/content.json": {Data: []byte(`{"cells": []}`)},
+	"B.qvlibrary/NEW.qvnotebook/N4.qvnote/meta.json":    {Data: []byte(`{"title": "Added", "uuid": "N4"}`)},
+	"B.qvlibrary/NEW.qvnotebook/N4.qvnote/content.json": {Data: []byte(`{"cells": []}`)},
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	a, err := quiver.ReadLibraryFS(diffOld, "A.qvlibrary", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := quiver.ReadLibraryFS(diffNew, "B.qvlibrary", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := quiver.Diff(a, a); !d.Empty() {
+		t.Errorf("Diff(a, a) = %v; want no changes", d)
+	}
+
+	d := quiver.Diff(a, b)
+	nbs := make(map[string]*quiver.NotebookDiff)
+	for _, nd := range d.Notebooks {
+		nbs[nd.UUID] = nd
+	}
+	if len(nbs) != 4 {
+		t.Errorf("len(d.Notebooks) = %v; want %v", len(nbs), 4)
+	}
+	if nd := nbs["NB1"]; nd == nil || !nd.Renamed || nd.Moved {
+		t.Errorf("NB1 should be renamed")
+	}
+	if nd := nbs["NB2"]; nd == nil || nd.Renamed || !nd.Moved || nd.OldParent != "NB1" || nd.NewParent != "" {
+		t.Errorf("NB2 should be moved to the root")
+	}
+	if nd := nbs["NEW"]; nd == nil || !nd.Added() {
+		t.Errorf("NEW should be added")
+	}
+	if nd := nbs["GONE"]; nd == nil || !nd.Removed() {
+		t.Errorf("GONE should be removed")
+	}
+
+	notes := make(map[string]*quiver.NoteDiff)
+	for _, nd := range d.Notes {
+		notes[nd.UUID] = nd
+	}
+	if len(notes) != 4 {
+		t.Errorf("len(d.Notes) = %v; want %v", len(notes), 4)
+	}
+	if nd := notes["N2"]; nd == nil || !nd.Moved || nd.OldNotebook.UUID != "NB1" || nd.NewNotebook.UUID != "NB2" || len(nd.Cells) != 0 {
+		t.Errorf("N2 should be moved to NB2")
+	}
+	if nd := notes["N3"]; nd == nil || !nd.Removed() {
+		t.Errorf("N3 should be removed")
+	}
+	if nd := notes["N4"]; nd == nil || !nd.Added() || nd.NewNotebook.UUID != "NEW" {
+		t.Errorf("N4 should be added")
+	}
+
+	nd := notes["N1"]
+	if nd == nil || !nd.Renamed || nd.Moved {
+		t.Fatalf("N1 should be renamed")
+	}
+	if strings.Join(nd.AddedTags, ",") != "new" || strings.Join(nd.RemovedTags, ",") != "old" {
+		t.Errorf("N1 tags: +%v -%v; want +new -old", nd.AddedTags, nd.RemovedTags)
+	}
+	var cells []string
+	for _, c := range nd.Cells {
+		cells = append(cells, string(c.Change))
+	}
+	if got, want := strings.Join(cells, ","), "inserted,modified,deleted"; got != want {
+		t.Fatalf("N1 cells = %v; want %v", got, want)
+	}
+	modified := nd.Cells[1]
+	if modified.OldIndex != 1 || modified.NewIndex != 2 {
+		t.Errorf("modified cell indexes = %v, %v; want 1, 2", modified.OldIndex, modified.NewIndex)
+	}
+	var lines []string
+	for _, l := range modified.Lines {
+		lines = append(lines, l.String())
+	}
+	if got, want := strings.Join(lines, "|"), " package main| func main() {|+\tprintln()| }"; got != want {
+		t.Errorf("modified cell lines = %q; want %q", got, want)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	t.Parallel()
+	diffs := quiver.DiffLines("x\ny\nz", "w\ny\nz")
+	var got []string
+	for _, d := range diffs {
+		got = append(got, d.String())
+	}
+	want := []string{"-x", "+w", " y", " z"}
+	if !stringSliceEqual(got, want) {
+		t.Errorf("quiver.DiffLines() = %q; want %q", got, want)
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	t.Parallel()
+	const n = 3000
+	var a, b []string
+	for i := 0; i < n; i++ {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+
+	// the lines between the common head and tail are too many to be matched: they are all replaced
+	diffs := quiver.DiffLines("head\n"+strings.Join(a, "\n")+"\ntail", "head\n"+strings.Join(b, "\n")+"\ntail")
+	if len(diffs) != 2*n+2 {
+		t.Fatalf("len(diffs) = %v; want %v", len(diffs), 2*n+2)
+	}
+	if diffs[0].String() != " head" || diffs[1].String() != "-a0" || diffs[n+1].String() != "+b0" || diffs[2*n+1].String() != " tail" {
+		t.Errorf("diffs = %v ... %v; want the lines replaced between head and tail", diffs[:2], diffs[2*n:])
+	}
+}
+
+func TestDiffNotebookWithoutMetadata(t *testing.T) {
+	t.Parallel()
+	note := buildNote("N1", "Note", nil, "a")
+	a := buildLibrary([]quiver.NotebookHierarchyInfo{{UUID: "NB1"}}, map[string][]*quiver.Note{"NB1": {note}})
+	b := &quiver.Library{Notebooks: []*quiver.Notebook{{Notes: []*quiver.Note{buildNote("N1", "Note", nil, "b")}}}}
+
+	// the notebooks without metadata are never compared
+	d := quiver.Diff(a, b)
+	if len(d.Notes) != 1 || d.Notes[0].Moved {
+		t.Errorf("d.Notes = %v; want a single note modified in place", d.Notes)
+	}
+}
@@ -0,0 +1,86 @@
+package quiver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The errors returned when an element does not have the expected Quiver layout.
+// The actual errors wrap them with more details: they should be checked with errors.Is.
+var (
+	ErrNotALibrary  = errors.New("not a Quiver library")
+	ErrNotANotebook = errors.New("not a Quiver notebook")
+	ErrNotANote     = errors.New("not a Quiver note")
+	ErrNotABundle   = errors.New("not a Quiver JSON bundle")
+)
+
+// FileKind tells which kind of Quiver file is being parsed.
+type FileKind string
+
+// The kinds of JSON files found in a Quiver library
+const (
+	LibraryMetadataFile  FileKind = "library metadata"
+	NotebookMetadataFile FileKind = "notebook metadata"
+	NoteMetadataFile     FileKind = "note metadata"
+	NoteContentFile      FileKind = "note content"
+)
+
+// ParseError is returned when a Quiver JSON file cannot be parsed.
+type ParseError struct {
+	// The path of the file: its OS path when loaded from one, its name in the fs.FS otherwise, or "" when parsing a
+	// stream with the ParseXXX functions.
+	Path string
+	// The kind of file being parsed.
+	Kind FileKind
+	// The offset (in bytes) of the error in the file, or -1 when unknown.
+	Offset int64
+	// The underlying error.
+	Err error
+}
+
+// Error returns the error message, with all the known details.
+func (e *ParseError) Error() string {
+	msg := "parsing " + string(e.Kind)
+	if e.Path != "" {
+		msg += fmt.Sprintf(" %q", e.Path)
+	}
+	if e.Offset >= 0 {
+		msg += fmt.Sprintf(" at offset %d", e.Offset)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// newParseError wraps the error returned when decoding a JSON file of the given kind.
+func newParseError(kind FileKind, err error) *ParseError {
+	pe := &ParseError{Kind: kind, Offset: -1, Err: err}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		pe.Offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		pe.Offset = typeErr.Offset
+	case err == io.EOF:
+		// an empty file is not valid JSON
+		pe.Offset = 0
+		pe.Err = io.ErrUnexpectedEOF
+	}
+
+	return pe
+}
+
+// withPath sets the path of the file on parse errors.
+func withPath(err error, path string) error {
+	if pe, ok := err.(*ParseError); ok && pe.Path == "" {
+		pe.Path = path
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package quiver_test
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ushu/quiver"
+)
+
+func TestParseError(t *testing.T) {
+	t.Parallel()
+
+	_, err := quiver.ParseNoteMetadata(strings.NewReader(`{"title": "T", "uuid": 12}`))
+	var pe *quiver.ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v; want a *quiver.ParseError", err)
+	}
+	if pe.Kind != quiver.NoteMetadataFile {
+		t.Errorf("pe.Kind = %q; want %q", pe.Kind, quiver.NoteMetadataFile)
+	}
+	if pe.Offset <= 0 {
+		t.Errorf("pe.Offset = %v; want a positive offset", pe.Offset)
+	}
+}
+
+func TestParseErrorPath(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"Broken.qvlibrary/NB.qvnotebook/meta.json":             {Data: []byte(`{"name": "Notebook", "uuid": "NB"}`)},
+		"Broken.qvlibrary/NB.qvnotebook/N.qvnote/meta.json":    {Data: []byte(`{"title": "N", "uuid": "N"}`)},
+		"Broken.qvlibrary/NB.qvnotebook/N.qvnote/content.json": {Data: []byte(`{"cells": [`)},
+	}
+
+	_, err := quiver.ReadLibraryFS(fsys, "Broken.qvlibrary", false)
+	var pe *quiver.ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v; want a *quiver.ParseError", err)
+	}
+	const path = "Broken.qvlibrary/NB.qvnotebook/N.qvnote/content.json"
+	if pe.Path != path {
+		t.Errorf("pe.Path = %q; want %q", pe.Path, path)
+	}
+	if pe.Kind != quiver.NoteContentFile {
+		t.Errorf("pe.Kind = %q; want %q", pe.Kind, quiver.NoteContentFile)
+	}
+}
+
+func TestParseErrorOSPath(t *testing.T) {
+	t.Parallel()
+	libPath, err := filepath.Abs(copyFixture(t, "Quiver.qvlibrary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := filepath.Join(libPath, "Quiver Test.qvnotebook", "D2A1CC36-CC97-4701-A895-EFC98EF47026.qvnote", "meta.json")
+	if err = ioutil.WriteFile(meta, []byte(`{"title": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the error tells which file of the library is broken
+	_, err = quiver.ReadLibrary(libPath, false)
+	var pe *quiver.ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v; want a *quiver.ParseError", err)
+	}
+	if pe.Path != meta {
+		t.Errorf("pe.Path = %q; want %q", pe.Path, meta)
+	}
+}
+
+func TestOSErrorPath(t *testing.T) {
+	t.Parallel()
+	libPath, err := filepath.Abs(copyFixture(t, "Quiver.qvlibrary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notePath := filepath.Join(libPath, "Quiver Test.qvnotebook", "D2A1CC36-CC97-4701-A895-EFC98EF47026.qvnote")
+	content := filepath.Join(notePath, "content.json")
+	if err = os.Remove(content); err != nil {
+		t.Fatal(err)
+	}
+
+	// the errors of the os package hold the full path of the files
+	for _, read := range []func() error{
+		func() error { _, err := quiver.ReadLibrary(libPath, false); return err },
+		func() error { _, err := quiver.ReadNote(notePath, false); return err },
+	} {
+		var pe *fs.PathError
+		if err := read(); !errors.As(err, &pe) || pe.Path != content {
+			t.Errorf("err = %v; want a *fs.PathError for %q", err, content)
+		}
+	}
+}
+
+func TestNotAQuiverElement(t *testing.T) {
+	t.Parallel()
+
+	_, err := quiver.ReadLibrary(fixturePath("Quiver.qvlibrary/Quiver Test.qvnotebook"), false)
+	if !errors.Is(err, quiver.ErrNotALibrary) {
+		t.Errorf("err = %v; want %v", err, quiver.ErrNotALibrary)
+	}
+	_, err = quiver.ReadNotebook(fixturePath("Quiver.qvlibrary"), false)
+	if !errors.Is(err, quiver.ErrNotANotebook) {
+		t.Errorf("err = %v; want %v", err, quiver.ErrNotANotebook)
+	}
+	_, err = quiver.ReadNote(fixturePath("Quiver.qvlibrary/Quiver Test.qvnotebook/meta.json"), false)
+	if !errors.Is(err, quiver.ErrNotANote) {
+		t.Errorf("err = %v; want %v", err, quiver.ErrNotANote)
+	}
+}
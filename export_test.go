@@ -0,0 +1,12 @@
+package quiver
+
+// WatchCache returns functions scanning the library at the given path like Watch does, and counting the files whose
+// hash is cached between the scans.
+func WatchCache(path string) (scan func() error, cached func() int) {
+	w := &watcher{path: path, hashes: make(map[string]fileHash)}
+	scan = func() error {
+		_, err := w.scan(nil)
+		return err
+	}
+	return scan, func() int { return len(w.hashes) }
+}
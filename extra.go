@@ -0,0 +1,320 @@
+package quiver
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// The unknown fields of the Quiver files (like the "title" of content.json, or the fields added by newer versions
+// of the app) are kept in the Extra maps of the data tree, and emitted again when marshaling, so that reading and
+// writing back a library does not lose any data.
+
+// The aliases below have no methods: they marshal with the default behavior.
+type (
+	libraryMetadata  LibraryMetadata
+	notebookMetadata NotebookMetadata
+	noteMetadata     NoteMetadata
+	noteContent      NoteContent
+	cell             Cell
+)
+
+// MarshalJSON marshals LibraryMetadata, along with its Extra fields.
+func (m *LibraryMetadata) MarshalJSON() ([]byte, error) {
+	return marshalObject((*libraryMetadata)(m), m.Extra)
+}
+
+// UnmarshalJSON unmarshals LibraryMetadata, keeping the unknown fields in Extra.
+func (m *LibraryMetadata) UnmarshalJSON(data []byte) (err error) {
+	m.Extra, err = unmarshalObject(data, (*libraryMetadata)(m))
+	return err
+}
+
+// MarshalJSON marshals NotebookMetadata, along with its Extra fields.
+func (m *NotebookMetadata) MarshalJSON() ([]byte, error) {
+	return marshalObject((*notebookMetadata)(m), m.Extra)
+}
+
+// UnmarshalJSON unmarshals NotebookMetadata, keeping the unknown fields in Extra.
+func (m *NotebookMetadata) UnmarshalJSON(data []byte) (err error) {
+	m.Extra, err = unmarshalObject(data, (*notebookMetadata)(m))
+	return err
+}
+
+// MarshalJSON marshals NoteMetadata, along with its Extra fields.
+func (m *NoteMetadata) MarshalJSON() ([]byte, error) {
+	return marshalObject((*noteMetadata)(m), m.Extra)
+}
+
+// UnmarshalJSON unmarshals NoteMetadata, keeping the unknown fields in Extra.
+func (m *NoteMetadata) UnmarshalJSON(data []byte) (err error) {
+	m.Extra, err = unmarshalObject(data, (*noteMetadata)(m))
+	return err
+}
+
+// MarshalJSON marshals NoteContent, along with its Extra fields.
+func (c *NoteContent) MarshalJSON() ([]byte, error) {
+	return marshalObject((*noteContent)(c), c.Extra)
+}
+
+// UnmarshalJSON unmarshals NoteContent, keeping the unknown fields in Extra.
+func (c *NoteContent) UnmarshalJSON(data []byte) (err error) {
+	c.Extra, err = unmarshalObject(data, (*noteContent)(c))
+	return err
+}
+
+// MarshalJSON marshals Cell, along with its Extra fields.
+func (c *Cell) MarshalJSON() ([]byte, error) {
+	return marshalObject((*cell)(c), c.Extra)
+}
+
+// UnmarshalJSON unmarshals Cell, keeping the unknown fields in Extra.
+func (c *Cell) UnmarshalJSON(data []byte) (err error) {
+	c.Extra, err = unmarshalObject(data, (*cell)(c))
+	return err
+}
+
+// Since they embed the metadata types, the elements of the data tree need their own (un)marshaling methods:
+// otherwise the ones of the embedded types would be used, dropping all the other fields.
+
+// MarshalJSON marshals the Library metadata, along with its notebooks.
+func (m *Library) MarshalJSON() ([]byte, error) {
+	aux := struct {
+		Notebooks []*Notebook `json:"notebooks"`
+	}{m.Notebooks}
+	return mergeObjects(m.LibraryMetadata, &aux)
+}
+
+// UnmarshalJSON unmarshals the Library metadata, along with its notebooks.
+func (m *Library) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Notebooks []*Notebook `json:"notebooks"`
+	}
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return err
+	}
+	meta := new(LibraryMetadata)
+	err = json.Unmarshal(data, meta)
+	if err != nil {
+		return err
+	}
+	meta.Extra = dropFields(meta.Extra, "notebooks")
+	if meta.Children == nil && meta.Extra == nil {
+		// the library was loaded without its metadata
+		meta = nil
+	}
+
+	m.LibraryMetadata = meta
+	m.Notebooks = aux.Notebooks
+	m.index = nil
+	return nil
+}
+
+// MarshalJSON marshals the Notebook metadata, along with its notes.
+func (n *Notebook) MarshalJSON() ([]byte, error) {
+	aux := struct {
+		Notes []*Note `json:"notes"`
+	}{n.Notes}
+	return mergeObjects(n.NotebookMetadata, &aux)
+}
+
+// UnmarshalJSON unmarshals the Notebook metadata, along with its notes.
+func (n *Notebook) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Notes []*Note `json:"notes"`
+	}
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return err
+	}
+	meta := new(NotebookMetadata)
+	err = json.Unmarshal(data, meta)
+	if err != nil {
+		return err
+	}
+	meta.Extra = dropFields(meta.Extra, "notes")
+
+	n.NotebookMetadata = meta
+	n.Notes = aux.Notes
+	return nil
+}
+
+// The field holding the unknown fields of the content in the JSON of notes, since they would clash with the ones of
+// the metadata (like "title").
+const contentExtraField = "content_extra"
+
+// MarshalJSON marshals the Note metadata, content and resources as a single object.
+// The unknown fields of the content are held in their own "content_extra" object.
+func (n *Note) MarshalJSON() ([]byte, error) {
+	aux := struct {
+		Resources    []*NoteResource            `json:"resources,omitempty"`
+		ContentExtra map[string]json.RawMessage `json:"content_extra,omitempty"`
+	}{Resources: n.Resources}
+	if n.NoteContent != nil {
+		aux.ContentExtra = n.NoteContent.Extra
+	}
+	return mergeObjects(n.NoteMetadata, (*noteContent)(n.NoteContent), &aux)
+}
+
+// UnmarshalJSON unmarshals the Note metadata, content and resources from a single object.
+// The unknown fields are kept in the Extra fields of the metadata, but the ones of "content_extra".
+func (n *Note) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	// the content and resources are split from the fields of the metadata
+	var resources []*NoteResource
+	content := new(NoteContent)
+	for _, f := range []struct {
+		name string
+		v    interface{}
+	}{
+		{"resources", &resources},
+		{"cells", &content.Cells},
+		{contentExtraField, &content.Extra},
+	} {
+		if raw, ok := fields[f.name]; ok {
+			err = json.Unmarshal(raw, f.v)
+			if err != nil {
+				return err
+			}
+			delete(fields, f.name)
+		}
+	}
+	meta := new(NoteMetadata)
+	meta.Extra, err = unmarshalFields(fields, (*noteMetadata)(meta))
+	if err != nil {
+		return err
+	}
+
+	n.NoteMetadata = meta
+	n.NoteContent = content
+	n.Resources = resources
+	return nil
+}
+
+// marshalObject marshals v (a struct), adding the extra fields it does not already hold.
+func marshalObject(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		if _, ok := fields[k]; !ok {
+			fields[k] = v
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// unmarshalObject unmarshals data into v (a pointer to a struct), and returns the fields unknown to v, if any.
+func unmarshalObject(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalFields(fields, v)
+}
+
+// unmarshalFields unmarshals the fields of an object into v (a pointer to a struct), and returns the ones unknown to
+// v, if any.
+func unmarshalFields(fields map[string]json.RawMessage, v interface{}) (map[string]json.RawMessage, error) {
+	s := reflect.ValueOf(v).Elem()
+	for _, f := range jsonFields(s.Type()) {
+		// like encoding/json, match the known fields case-insensitively, preferring the exact name
+		raw, ok := fields[f.name]
+		for k, r := range fields {
+			if strings.EqualFold(k, f.name) {
+				if !ok {
+					raw, ok = r, true
+				}
+				delete(fields, k)
+			}
+		}
+		if !ok {
+			continue
+		}
+		err := json.Unmarshal(raw, s.Field(f.index).Addr().Interface())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dropFields(fields), nil
+}
+
+// mergeObjects marshals all the values (structs or nil pointers) as a single object.
+// When a field is found in several values, the first one wins.
+func mergeObjects(values ...interface{}) ([]byte, error) {
+	merged := make(map[string]json.RawMessage)
+	for _, v := range values {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]json.RawMessage
+		err = json.Unmarshal(data, &fields)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range fields {
+			if _, ok := merged[k]; !ok {
+				merged[k] = v
+			}
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// dropFields removes the given fields, and returns nil when no field is left.
+func dropFields(fields map[string]json.RawMessage, names ...string) map[string]json.RawMessage {
+	for _, name := range names {
+		delete(fields, name)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// jsonField is a JSON field of a struct type.
+type jsonField struct {
+	name  string
+	index int
+}
+
+// jsonFields returns the JSON fields of the struct type t.
+func jsonFields(t reflect.Type) []jsonField {
+	fields := make([]jsonField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if i := strings.IndexByte(tag, ','); i >= 0 {
+				tag = tag[:i]
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, jsonField{name, i})
+	}
+	return fields
+}
@@ -0,0 +1,166 @@
+package quiver_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ushu/quiver"
+)
+
+const extraNoteMetadata = `{
+  "created_at": 1,
+  "tags": ["go"],
+  "title": "Extra",
+  "updated_at": 2,
+  "uuid": "N",
+  "pinned": true
+}`
+
+const extraNoteContent = `{
+  "title": "Extra",
+  "cells": [
+    {"type": "code", "language": "go", "data": "package main", "lineNumbers": {"start": 1}}
+  ],
+  "version": 4
+}`
+
+func TestUnknownFieldsRoundTrip(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"Extra.qvlibrary/meta.json":                           {Data: []byte(`{"children": [{"uuid": "NB", "children": []}], "sync": "icloud"}`)},
+		"Extra.qvlibrary/NB.qvnotebook/meta.json":             {Data: []byte(`{"name": "Notebook", "uuid": "NB", "color": "red"}`)},
+		"Extra.qvlibrary/NB.qvnotebook/N.qvnote/meta.json":    {Data: []byte(extraNoteMetadata)},
+		"Extra.qvlibrary/NB.qvnotebook/N.qvnote/content.json": {Data: []byte(extraNoteContent)},
+	}
+	lib, err := quiver.ReadLibraryFS(fsys, "Extra.qvlibrary", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	note := lib.NoteByUUID("N")
+	if got := string(note.NoteMetadata.Extra["pinned"]); got != "true" {
+		t.Errorf("note.NoteMetadata.Extra[pinned] = %q; want %q", got, "true")
+	}
+	if got := string(note.Cells[0].Extra["lineNumbers"]); got != `{"start": 1}` {
+		t.Errorf("note.Cells[0].Extra[lineNumbers] = %q; want %q", got, `{"start": 1}`)
+	}
+
+	// writing the library back gives the same JSON
+	outPath := filepath.Join(t.TempDir(), "Extra.qvlibrary")
+	err = quiver.WriteLibrary(outPath, lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, f := range fsys {
+		data, err := os.ReadFile(filepath.Join(outPath, filepath.FromSlash(name[len("Extra.qvlibrary/"):])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !jsonEqual(t, data, f.Data) {
+			t.Errorf("%v = %s; want %s", name, data, f.Data)
+		}
+	}
+}
+
+func TestNoteUnknownFieldsJSON(t *testing.T) {
+	t.Parallel()
+
+	var meta quiver.NoteMetadata
+	var content quiver.NoteContent
+	if err := json.Unmarshal([]byte(extraNoteMetadata), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(extraNoteContent), &content); err != nil {
+		t.Fatal(err)
+	}
+	note := &quiver.Note{NoteMetadata: &meta, NoteContent: &content}
+
+	// the unknown fields of the content do not end up in the metadata
+	data, err := json.Marshal(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out quiver.Note
+	err = json.Unmarshal(data, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.NoteMetadata, note.NoteMetadata) {
+		t.Errorf("out.NoteMetadata = %+v; want %+v", out.NoteMetadata, note.NoteMetadata)
+	}
+	got, err := json.Marshal(out.NoteContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !jsonEqual(t, got, []byte(extraNoteContent)) {
+		t.Errorf("json.Marshal(out.NoteContent) = %s; want %s", got, extraNoteContent)
+	}
+	if got := string(out.NoteContent.Extra["version"]); got != "4" {
+		t.Errorf("out.NoteContent.Extra[version] = %q; want %q", got, "4")
+	}
+}
+
+func TestUnknownFieldsMarshal(t *testing.T) {
+	t.Parallel()
+
+	var c quiver.Cell
+	err := json.Unmarshal([]byte(`{"type": "text", "data": "Hello", "style": "bold"}`), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Extra) != 1 {
+		t.Errorf("c.Extra = %v; want only the style field", c.Extra)
+	}
+	data, err := json.Marshal(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !jsonEqual(t, data, []byte(`{"type": "text", "data": "Hello", "style": "bold"}`)) {
+		t.Errorf("json.Marshal(c) = %s", data)
+	}
+
+	// the elements of the data tree keep their own fields
+	lib := &quiver.Library{
+		LibraryMetadata: &quiver.LibraryMetadata{Children: []quiver.NotebookHierarchyInfo{}},
+		Notebooks: []*quiver.Notebook{{
+			NotebookMetadata: &quiver.NotebookMetadata{Name: "Notebook", UUID: "NB"},
+			Notes:            []*quiver.Note{},
+		}},
+	}
+	data, err = json.Marshal(lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"children": [], "notebooks": [{"name": "Notebook", "uuid": "NB", "notes": []}]}`
+	if !jsonEqual(t, data, []byte(want)) {
+		t.Errorf("json.Marshal(lib) = %s; want %s", data, want)
+	}
+	var out quiver.Library
+	err = json.Unmarshal(data, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.LibraryMetadata.Extra != nil || out.Notebooks[0].NotebookMetadata.Extra != nil {
+		t.Errorf("unexpected extra fields after unmarshaling")
+	}
+	if !reflect.DeepEqual(out.Notebooks[0].NotebookMetadata, lib.Notebooks[0].NotebookMetadata) {
+		t.Errorf("out.Notebooks[0] = %v; want %v", out.Notebooks[0].NotebookMetadata, lib.Notebooks[0].NotebookMetadata)
+	}
+}
+
+// jsonEqual tells if a and b hold the same JSON value.
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,88 @@
+package quiver
+
+// libraryIndex holds the lookup tables of a Library.
+type libraryIndex struct {
+	notes      map[string]*Note
+	notebooks  map[string]*Notebook
+	notebookOf map[*Note]*Notebook
+	tags       map[string][]*Note
+	backlinks  map[string][]*Note
+}
+
+// Reindex rebuilds the lookup tables used by NoteByUUID, NotebookByUUID, NotebookOf, NotesByTag and Backlinks.
+//
+// The tables are built when the library is loaded (or on the first lookup for the libraries built by hand): Reindex
+// should only be called after changing the notebooks, the notes, their tags or their cells. The lookups are safe
+// for concurrent use, but Reindex should not be called while they run.
+func (m *Library) Reindex() {
+	idx := m.buildIndex()
+
+	m.indexMu.Lock()
+	defer m.indexMu.Unlock()
+	m.index = idx
+}
+
+func (m *Library) buildIndex() *libraryIndex {
+	idx := &libraryIndex{
+		notes:      make(map[string]*Note),
+		notebooks:  make(map[string]*Notebook, len(m.Notebooks)),
+		notebookOf: make(map[*Note]*Notebook),
+		tags:       make(map[string][]*Note),
+		backlinks:  make(map[string][]*Note),
+	}
+
+	for _, nb := range m.Notebooks {
+		if nb.NotebookMetadata != nil {
+			if _, ok := idx.notebooks[nb.UUID]; !ok {
+				idx.notebooks[nb.UUID] = nb
+			}
+		}
+		for _, n := range nb.Notes {
+			idx.notebookOf[n] = nb
+			if n.NoteMetadata == nil {
+				continue
+			}
+			if _, ok := idx.notes[n.UUID]; !ok {
+				idx.notes[n.UUID] = n
+			}
+			for _, t := range n.Tags {
+				idx.tags[t] = append(idx.tags[t], n)
+			}
+			for _, uuid := range n.OutgoingLinks() {
+				idx.backlinks[uuid] = append(idx.backlinks[uuid], n)
+			}
+		}
+	}
+
+	return idx
+}
+
+func (m *Library) lookup() *libraryIndex {
+	m.indexMu.Lock()
+	defer m.indexMu.Unlock()
+
+	if m.index == nil {
+		m.index = m.buildIndex()
+	}
+	return m.index
+}
+
+// NoteByUUID returns the note with the given UUID, or nil if not found.
+func (m *Library) NoteByUUID(uuid string) *Note {
+	return m.lookup().notes[uuid]
+}
+
+// NotebookByUUID returns the notebook with the given UUID, or nil if not found.
+func (m *Library) NotebookByUUID(uuid string) *Notebook {
+	return m.lookup().notebooks[uuid]
+}
+
+// NotebookOf returns the notebook holding the given note, or nil if not found.
+func (m *Library) NotebookOf(n *Note) *Notebook {
+	return m.lookup().notebookOf[n]
+}
+
+// NotesByTag returns all the notes with the given tag, in library order.
+func (m *Library) NotesByTag(tag string) []*Note {
+	return m.lookup().tags[tag]
+}
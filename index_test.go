@@ -0,0 +1,107 @@
+package quiver_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ushu/quiver"
+)
+
+func TestLibraryLookups(t *testing.T) {
+	t.Parallel()
+	lib, err := quiver.ReadLibrary(fixturePath("Quiver.qvlibrary"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const UUID = "73385592-0CAB-41E5-9045-AEC528C2915A"
+	note := lib.NoteByUUID(UUID)
+	if note == nil || note.UUID != UUID {
+		t.Fatalf("lib.NoteByUUID(%q) = %v", UUID, note)
+	}
+	if lib.NoteByUUID("MISSING") != nil {
+		t.Errorf("lib.NoteByUUID(%q) should be nil", "MISSING")
+	}
+
+	nb := lib.NotebookByUUID("FIXTURE")
+	if nb == nil || nb.Name != "Quiver Test" {
+		t.Fatalf("lib.NotebookByUUID(%q) = %v", "FIXTURE", nb)
+	}
+	if lib.NotebookOf(note) != nb {
+		t.Errorf("lib.NotebookOf(note) = %v; want %v", lib.NotebookOf(note), nb)
+	}
+
+	tagged := lib.NotesByTag("tutorial")
+	if len(tagged) != 1 || tagged[0].UUID != "D2A1CC36-CC97-4701-A895-EFC98EF47026" {
+		t.Errorf("lib.NotesByTag(%q) = %v; want a single note", "tutorial", tagged)
+	}
+}
+
+func TestLibraryReindex(t *testing.T) {
+	t.Parallel()
+	note := &quiver.Note{NoteMetadata: &quiver.NoteMetadata{UUID: "N", Tags: []string{"go"}}}
+	nb := &quiver.Notebook{NotebookMetadata: &quiver.NotebookMetadata{UUID: "NB"}}
+	lib := &quiver.Library{Notebooks: []*quiver.Notebook{nb}}
+
+	// Lookups work on libraries built by hand
+	if lib.NoteByUUID("N") != nil {
+		t.Fatalf("lib.NoteByUUID(%q) should be nil", "N")
+	}
+
+	// And are updated by Reindex
+	nb.Notes = append(nb.Notes, note)
+	lib.Reindex()
+	if lib.NoteByUUID("N") != note {
+		t.Errorf("lib.NoteByUUID(%q) = %v; want %v", "N", lib.NoteByUUID("N"), note)
+	}
+	if len(lib.NotesByTag("go")) != 1 {
+		t.Errorf("len(lib.NotesByTag(%q)) = %v; want %v", "go", len(lib.NotesByTag("go")), 1)
+	}
+}
+
+func TestLibraryConcurrentLookups(t *testing.T) {
+	t.Parallel()
+	note := &quiver.Note{NoteMetadata: &quiver.NoteMetadata{UUID: "N"}}
+	lib := &quiver.Library{Notebooks: []*quiver.Notebook{{
+		NotebookMetadata: &quiver.NotebookMetadata{UUID: "NB"},
+		Notes:            []*quiver.Note{note},
+	}}}
+
+	// the index is built by the first lookup, whichever it is
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if lib.NoteByUUID("N") != note {
+				t.Errorf("lib.NoteByUUID(%q) should be found", "N")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWalkNotebooksHierarchyWithoutReindex(t *testing.T) {
+	t.Parallel()
+	parent := &quiver.Notebook{NotebookMetadata: &quiver.NotebookMetadata{UUID: "PARENT"}}
+	lib := &quiver.Library{
+		LibraryMetadata: &quiver.LibraryMetadata{Children: []quiver.NotebookHierarchyInfo{
+			{UUID: "PARENT", Children: []quiver.NotebookHierarchyInfo{{UUID: "CHILD"}}},
+		}},
+		Notebooks: []*quiver.Notebook{parent},
+	}
+	lib.Reindex()
+
+	// the notebooks added after the last Reindex are still walked with their parents
+	child := &quiver.Notebook{NotebookMetadata: &quiver.NotebookMetadata{UUID: "CHILD"}}
+	lib.Notebooks = append(lib.Notebooks, child)
+	err := lib.WalkNotebooksHierarchy(func(nb *quiver.Notebook, parents []*quiver.Notebook) error {
+		if nb == child && (len(parents) != 1 || parents[0] != parent) {
+			t.Errorf("parents of %q = %v; want %q", nb.UUID, parents, "PARENT")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
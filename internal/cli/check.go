@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/ushu/quiver"
+)
+
+var checkCommand = &command{
+	name:    "check",
+	aliases: []string{"fsck"},
+	args:    "QUIVER_LIBRARY",
+	summary: "Check the consistency of a library",
+	help:    checkHelp,
+	globals: libraryOption | formatOption,
+	flags: func(fs *flag.FlagSet, opts *options) {
+		fs.BoolVar(&opts.checkRepair, "repair", false, "repair the problems that can be fixed automatically")
+	},
+	run: runCheck,
+}
+
+// The help about the output
+const checkHelp = `Each problem is printed on its own line, as tab-separated fields: kind, path and message.
+With -repair, a fourth field tells the outcome: "repaired", "not-repairable" or "failed: ERROR".
+With -format json, the problems are printed as an array of objects with the "kind", "path", "uuid", "message"
+(and "repair") fields.
+
+The whole library is checked, including the Trash. The exit status is 1 when some problems are left.`
+
+// checkResult is a problem, as printed in JSON
+type checkResult struct {
+	quiver.Problem
+	Repair string `json:"repair,omitempty"`
+}
+
+func runCheck(fs *flag.FlagSet, opts *options) error {
+	path, args, err := libraryArgs(fs, opts)
+	if err != nil || len(args) != 0 {
+		return errUsage
+	}
+
+	// resources are listed, so that they follow the repaired directories
+	opts.includeTrash = true
+	library, err := loadLibrary(path, quiver.LazyResources, opts)
+	if err != nil {
+		return err
+	}
+
+	left := 0
+	results := []checkResult{}
+	for _, p := range quiver.Check(library) {
+		r := checkResult{Problem: p}
+		if opts.checkRepair {
+			switch err := p.Repair(); {
+			case err == nil:
+				r.Repair = "repaired"
+			case err == quiver.ErrNotRepairable:
+				r.Repair = "not-repairable"
+			default:
+				r.Repair = "failed: " + err.Error()
+			}
+		}
+		if r.Repair != "repaired" {
+			left++
+		}
+
+		switch {
+		case opts.format == jsonFormat:
+			results = append(results, r)
+		case r.Repair != "":
+			fmt.Fprintf(stdout, "%v\t%v\n", p, r.Repair)
+		default:
+			fmt.Fprintln(stdout, p)
+		}
+	}
+	if opts.format == jsonFormat {
+		if err = printJSON(results); err != nil {
+			return err
+		}
+	}
+
+	if left > 0 {
+		return exitCode(ExitFound)
+	}
+	return nil
+}
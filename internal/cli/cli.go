@@ -0,0 +1,437 @@
+/*
+Package cli implements the commands of the quiver tool, shared by the quiver binary and the older single-purpose
+binaries (quiver_to_json, quiver_to_markdown), which are thin wrappers around it.
+
+All the commands accept the same global options, when they apply:
+
+	-library PATH       the library to work on, instead of the first argument
+	-include-trash      also work on the notes in the Trash (skipped by default)
+	-notebook NAME      only keep the notes of the notebook with the given name (or UUID), and of its sub-notebooks
+	-tag TAG            only keep the notes with the given tag
+	-format FORMAT      the output format: text (the default) or json
+	-lenient            skip malformed notes and junk files instead of failing
+
+The -notebook and -tag options can be repeated: a note is kept when it belongs to any of the notebooks, and has all
+the tags.
+
+The exit status is the same for all the commands: 0 on success, 1 when the command ran but found something to report
+(like differences, problems, conflicts or no matching notes), and 2 on errors and invalid arguments.
+*/
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ushu/quiver"
+)
+
+// The exit statuses of the commands
+const (
+	// ExitOK is returned when the command succeeded.
+	ExitOK = 0
+	// ExitFound is returned when the command succeeded, but found something to report.
+	ExitFound = 1
+	// ExitError is returned on errors, and when the command is called with invalid arguments.
+	ExitError = 2
+)
+
+// The streams used by the commands, replaced in tests.
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// command is a subcommand of the tool
+type command struct {
+	// The name of the command.
+	name string
+	// Other names of the command.
+	aliases []string
+	// The arguments of the command, as displayed in the usage.
+	args string
+	// A one-line description of the command.
+	summary string
+	// More details about the command, displayed after the options.
+	help string
+	// The global options accepted by the command.
+	globals globalOptions
+	// The function setting the flags of the command, into opts.
+	flags func(fs *flag.FlagSet, opts *options)
+	// The function running the command with the remaining arguments.
+	run func(fs *flag.FlagSet, opts *options) error
+	// The subcommands, for commands like "export" which only dispatch to them.
+	subcommands []*command
+}
+
+// globalOptions tells which global options are accepted by a command.
+type globalOptions int
+
+const (
+	// the command works on a single library: -library
+	libraryOption globalOptions = 1 << iota
+	// the command works on the notes of the library: -include-trash, -notebook and -tag
+	filterOptions
+	// the command can output JSON: -format
+	formatOption
+)
+
+// errUsage is returned by commands when called with invalid arguments.
+var errUsage = errors.New("invalid arguments")
+
+// exitCode is returned by commands to exit with the given status, without printing anything more.
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(c))
+}
+
+var commands = []*command{
+	lsCommand,
+	showCommand,
+	searchCommand,
+	statsCommand,
+	checkCommand,
+	exportCommand,
+	diffCommand,
+	mergeCommand,
+}
+
+// options holds the options of a single run of a command: the global options, and the ones of the commands.
+type options struct {
+	// The library to work on.
+	library string
+	// Tells the tool to also load the notes in the Trash.
+	includeTrash bool
+	// The notebooks and tags of the notes to keep.
+	notebooks, tags stringList
+	// The output format.
+	format string
+	// Tells the tool to skip malformed notes instead of failing.
+	lenient bool
+
+	// The options of the commands, see their flags.
+	checkRepair   bool
+	diffStat      bool
+	jsonResources bool
+	markdown      markdownOptions
+	mergeForce    bool
+	searchMax     int
+}
+
+// The output formats
+const (
+	textFormat = "text"
+	jsonFormat = "json"
+)
+
+// stringList is a flag which can be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// Main runs the command given by the arguments (without the program name), and returns the exit status.
+func Main(args []string) int {
+	if len(args) < 1 {
+		usage()
+		return ExitError
+	}
+
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			if cmd := findCommand(commands, args[1]); cmd != nil {
+				name := cmd.name
+				if cmd.subcommands != nil && len(args) > 2 {
+					if sub := findCommand(cmd.subcommands, args[2]); sub != nil {
+						cmd, name = sub, name+" "+sub.name
+					}
+				}
+				newFlagSet(name, cmd, &options{}).Usage()
+				return ExitOK
+			}
+		}
+		usage()
+		return ExitOK
+	case "version", "-v":
+		fmt.Fprintf(stdout, "v%v\n", quiver.Version)
+		return ExitOK
+	}
+
+	cmd := findCommand(commands, name)
+	if cmd == nil {
+		fmt.Fprintf(stdout, "Unknown command %q\n\n", name)
+		usage()
+		return ExitError
+	}
+	return Run(cmd.name, args[1:])
+}
+
+// Run runs the command with the given name (like "export markdown" for subcommands) with its arguments, and returns
+// the exit status.
+func Run(name string, args []string) int {
+	var cmd *command
+	list := commands
+	for _, n := range strings.Fields(name) {
+		if cmd = findCommand(list, n); cmd == nil {
+			fmt.Fprintf(stdout, "Unknown command %q\n", name)
+			return ExitError
+		}
+		list = cmd.subcommands
+	}
+
+	if cmd.subcommands != nil {
+		if len(args) < 1 || findCommand(cmd.subcommands, args[0]) == nil {
+			newFlagSet(name, cmd, &options{}).Usage()
+			return ExitError
+		}
+		return Run(name+" "+args[0], args[1:])
+	}
+
+	opts := &options{}
+	fs := newFlagSet(name, cmd, opts)
+	if err := fs.Parse(args); err != nil {
+		return ExitError
+	}
+	if opts.format != textFormat && opts.format != jsonFormat {
+		fmt.Fprintf(stdout, "Unknown format %q\n", opts.format)
+		return ExitError
+	}
+
+	err := cmd.run(fs, opts)
+	if err == errUsage {
+		fs.Usage()
+		return ExitError
+	}
+	if code, ok := err.(exitCode); ok {
+		return int(code)
+	}
+	if err != nil {
+		fmt.Fprintln(stdout, err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+func findCommand(list []*command, name string) *command {
+	for _, cmd := range list {
+		if cmd.name == name {
+			return cmd
+		}
+		for _, a := range cmd.aliases {
+			if a == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// newFlagSet returns the flags of the command, with the given full name (like "export markdown"), parsed into opts.
+func newFlagSet(name string, cmd *command, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stdout)
+
+	opts.format = textFormat
+	fs.BoolVar(&opts.lenient, "lenient", false, "skip malformed notes and junk files")
+	if cmd.globals&libraryOption != 0 {
+		fs.StringVar(&opts.library, "library", "", "the `path` of the library, instead of the first argument")
+	}
+	if cmd.globals&filterOptions != 0 {
+		fs.BoolVar(&opts.includeTrash, "include-trash", false, "also include the notes in the Trash")
+		fs.Var(&opts.notebooks, "notebook", "only keep the notes of the notebook with the given `name` (or UUID)")
+		fs.Var(&opts.tags, "tag", "only keep the notes with the given `tag`")
+	}
+	if cmd.globals&formatOption != 0 {
+		fs.StringVar(&opts.format, "format", textFormat, "the output `format`: text or json")
+	}
+	if cmd.flags != nil {
+		cmd.flags(fs, opts)
+	}
+
+	fs.Usage = func() {
+		if cmd.subcommands != nil {
+			fmt.Fprintf(stdout, "Usage: quiver %v COMMAND [OPTIONS] ARGS...\n\n", name)
+			fmt.Fprintln(stdout, cmd.summary)
+			fmt.Fprintln(stdout)
+			fmt.Fprintln(stdout, "Commands:")
+			for _, sub := range cmd.subcommands {
+				fmt.Fprintf(stdout, "  %-10v %v\n", sub.name, sub.summary)
+			}
+			return
+		}
+		fmt.Fprintf(stdout, "Usage: quiver %v [OPTIONS] %v\n\n", fs.Name(), cmd.args)
+		fmt.Fprintln(stdout, cmd.summary)
+		fmt.Fprintln(stdout)
+		fmt.Fprintln(stdout, "Options:")
+		fs.PrintDefaults()
+		if cmd.help != "" {
+			fmt.Fprintln(stdout)
+			fmt.Fprintln(stdout, cmd.help)
+		}
+	}
+	return fs
+}
+
+func usage() {
+	fmt.Fprintln(stdout, "Usage: quiver COMMAND [OPTIONS] ARGS...")
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(stdout, "  %-10v %v\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, `Run "quiver help COMMAND" for the details of each command.`)
+}
+
+// libraryArgs returns the path of the library given with -library, or else the first argument, along with the other
+// arguments.
+func libraryArgs(fs *flag.FlagSet, opts *options) (string, []string, error) {
+	if opts.library != "" {
+		return opts.library, fs.Args(), nil
+	}
+	if fs.NArg() == 0 {
+		return "", nil, errUsage
+	}
+	return fs.Arg(0), fs.Args()[1:], nil
+}
+
+// loadLibrary loads the library at the given path, as configured by the global options.
+func loadLibrary(path string, resources quiver.ResourceMode, opts *options) (*quiver.Library, error) {
+	ro := &quiver.ReadOptions{Resources: resources, Lenient: opts.lenient, ExcludeTrash: !opts.includeTrash}
+	library, err := quiver.ReadLibraryContext(context.Background(), path, ro)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range library.Warnings {
+		fmt.Fprintf(stderr, "skipped %v\n", w)
+	}
+	filterLibrary(library, opts)
+	return library, nil
+}
+
+// filterLibrary removes the notes not matching the -notebook and -tag options, and the notebooks left empty.
+func filterLibrary(lib *quiver.Library, opts *options) {
+	if len(opts.notebooks) == 0 && len(opts.tags) == 0 {
+		return
+	}
+
+	keep := make(map[string]bool)
+	lib.WalkNotebooksHierarchy(func(nb *quiver.Notebook, parents []*quiver.Notebook) error {
+		if !matchNotebook(append(parents, nb), opts.notebooks) {
+			nb.Notes = nil
+			return nil
+		}
+		notes := nb.Notes[:0]
+		for _, n := range nb.Notes {
+			if matchTags(n, opts.tags) {
+				notes = append(notes, n)
+			}
+		}
+		nb.Notes = notes
+		keep[nb.UUID] = len(notes) > 0
+		return nil
+	})
+
+	notebooks := lib.Notebooks[:0]
+	for _, nb := range lib.Notebooks {
+		if nb.NotebookMetadata != nil && keep[nb.UUID] {
+			notebooks = append(notebooks, nb)
+		}
+	}
+	lib.Notebooks = notebooks
+	if lib.LibraryMetadata != nil {
+		meta := *lib.LibraryMetadata
+		meta.Children = pruneHierarchy(meta.Children, keep)
+		lib.LibraryMetadata = &meta
+	}
+	lib.Reindex()
+}
+
+// matchNotebook returns true when one of the notebooks (a notebook and its parents) matches one of the names given
+// with -notebook.
+func matchNotebook(notebooks []*quiver.Notebook, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, nb := range notebooks {
+		for _, name := range names {
+			if strings.EqualFold(nb.Name, name) || strings.EqualFold(nb.UUID, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchTags returns true when the note has all the tags given with -tag.
+func matchTags(n *quiver.Note, tags []string) bool {
+	for _, t := range tags {
+		found := false
+		for _, tag := range n.Tags {
+			if strings.EqualFold(tag, t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// pruneHierarchy removes the notebooks which are not kept, moving their children up.
+func pruneHierarchy(children []quiver.NotebookHierarchyInfo, keep map[string]bool) []quiver.NotebookHierarchyInfo {
+	pruned := []quiver.NotebookHierarchyInfo{}
+	for _, c := range children {
+		sub := pruneHierarchy(c.Children, keep)
+		if keep[c.UUID] {
+			pruned = append(pruned, quiver.NotebookHierarchyInfo{UUID: c.UUID, Children: sub})
+		} else {
+			pruned = append(pruned, sub...)
+		}
+	}
+	return pruned
+}
+
+// printJSON outputs the value as indented JSON.
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// wrap cuts the text in lines of at most width characters, prefixed by indent.
+func wrap(s string, width int, indent string) string {
+	var lines []string
+	var line string
+	for _, w := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(w) > width {
+			lines = append(lines, indent+line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += w
+	}
+	if line != "" {
+		lines = append(lines, indent+line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,234 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ushu/quiver"
+	"github.com/ushu/quiver/internal/cli"
+)
+
+var fixture = filepath.Join("..", "..", "testdata", "Quiver.qvlibrary")
+
+// run runs the command, and returns its exit status and output
+func run(args ...string) (int, string) {
+	var out bytes.Buffer
+	cli.SetOutput(&out)
+	code := cli.Main(args)
+	return code, out.String()
+}
+
+func TestExitCodes(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{}, cli.ExitError},
+		{[]string{"version"}, cli.ExitOK},
+		{[]string{"unknown"}, cli.ExitError},
+		{[]string{"ls"}, cli.ExitError},
+		{[]string{"ls", "-format", "yaml", fixture}, cli.ExitError},
+		{[]string{"ls", filepath.Join(os.TempDir(), "Missing.qvlibrary")}, cli.ExitError},
+		{[]string{"ls", fixture}, cli.ExitOK},
+		{[]string{"search", fixture, "link"}, cli.ExitOK},
+		{[]string{"search", fixture, "nothing-matches-this"}, cli.ExitFound},
+		{[]string{"show", fixture, "Nothing"}, cli.ExitFound},
+		{[]string{"check", fixture}, cli.ExitFound},
+		{[]string{"export"}, cli.ExitError},
+	} {
+		if code, out := run(tt.args...); code != tt.code {
+			t.Errorf("quiver %v: exit status %v; want %v\n%v", strings.Join(tt.args, " "), code, tt.code, out)
+		}
+	}
+}
+
+func TestLs(t *testing.T) {
+	_, out := run("ls", fixture)
+	want := "Quiver Test/ (FIXTURE)\n  Tags (73385592-0CAB-41E5-9045-AEC528C2915A)\n"
+	if !strings.HasPrefix(out, want) {
+		t.Errorf("quiver ls = %q; want it to start with %q", out, want)
+	}
+
+	// with filters
+	_, out = run("ls", "-format", "json", "-library", fixture, "-tag", "retest", "-notebook", "quiver test")
+	var notebooks []struct {
+		UUID  string
+		Notes []struct{ Title string }
+	}
+	if err := json.Unmarshal([]byte(out), &notebooks); err != nil {
+		t.Fatal(err)
+	}
+	if len(notebooks) != 1 || len(notebooks[0].Notes) != 1 || notebooks[0].Notes[0].Title != "Tags" {
+		t.Errorf("quiver ls -tag retest = %v; want the %q note", notebooks, "Tags")
+	}
+
+	_, out = run("ls", "-format", "json", "-notebook", "Other", fixture)
+	if strings.TrimSpace(out) != "[]" {
+		t.Errorf("quiver ls -notebook Other = %v; want no notebooks", out)
+	}
+}
+
+func TestShow(t *testing.T) {
+	code, out := run("show", fixture, "text CELLS")
+	if code != cli.ExitOK || !strings.HasPrefix(out, "# Text cells\n") {
+		t.Errorf("quiver show = %q; want the %q note", out, "Text cells")
+	}
+
+	_, out = run("show", "-format", "json", fixture, "73385592-0CAB-41E5-9045-AEC528C2915A")
+	var note quiver.Note
+	if err := json.Unmarshal([]byte(out), &note); err != nil {
+		t.Fatal(err)
+	}
+	if note.Title != "Tags" || len(note.Cells) != 1 {
+		t.Errorf("quiver show -format json = %v; want the %q note", out, "Tags")
+	}
+}
+
+func TestStats(t *testing.T) {
+	_, out := run("stats", "-format", "json", fixture)
+	var stats struct {
+		Notebooks, Notes, Tags, Resources int
+	}
+	if err := json.Unmarshal([]byte(out), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Notebooks != 1 || stats.Notes != 3 || stats.Tags != 4 || stats.Resources != 2 {
+		t.Errorf("quiver stats = %+v; want 1 notebook, 3 notes, 4 tags and 2 resources", stats)
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+
+	bundle := filepath.Join(dir, "quiver.json")
+	if code, out := run("export", "json", "-tag", "retest", fixture, bundle); code != cli.ExitOK {
+		t.Fatalf("quiver export json: exit status %v\n%v", code, out)
+	}
+	f, err := os.Open(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lib, err := quiver.DecodeBundle(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lib.Notebooks) != 1 || len(lib.Notebooks[0].Notes) != 1 {
+		t.Errorf("quiver export json -tag retest should only export the %q note", "Tags")
+	}
+
+	md := filepath.Join(dir, "markdown")
+	if code, out := run("export", "markdown", fixture, md); code != cli.ExitOK {
+		t.Fatalf("quiver export markdown: exit status %v\n%v", code, out)
+	}
+	for _, p := range []string{"Tags.md", "Text cells.md", "_resources/1C3392AA-54E7-4EA3-A129-1C20F208B029.jpg"} {
+		if _, err := os.Stat(filepath.Join(md, "Quiver Test", p)); err != nil {
+			t.Errorf("quiver export markdown should write %v", p)
+		}
+	}
+
+	// the second run does not rewrite anything
+	_, out := run("export", "markdown", fixture, md)
+	if !strings.Contains(out, "(0 written, 0 moved, 5 unchanged, 0 deleted)") {
+		t.Errorf("quiver export markdown = %q; want all the files unchanged", out)
+	}
+
+	// unless the front matter is added
+	_, out = run("export", "markdown", "-front-matter", fixture, md)
+	if !strings.Contains(out, "(3 written, 0 moved, 2 unchanged, 0 deleted)") {
+		t.Errorf("quiver export markdown -front-matter = %q; want the notes rewritten", out)
+	}
+	data, err := os.ReadFile(filepath.Join(md, "Quiver Test", "Tags.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `---
+uuid: 73385592-0CAB-41E5-9045-AEC528C2915A
+title: "Tags"
+notebook: "Quiver Test"
+tags: ["retest", "tags", "test"]
+created_at: 2017-09-18T10:40:10Z
+updated_at: 2017-09-18T10:40:18Z
+---
+
+# Tags
+
+This cell has tags.
+`
+	if string(data) != want {
+		t.Errorf("quiver export markdown -front-matter wrote %q; want %q", data, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "Merged.qvlibrary")
+
+	if code, o := run("merge", fixture, fixture, fixture, out); code != cli.ExitOK {
+		t.Fatalf("quiver merge: exit status %v\n%v", code, o)
+	}
+	resource := filepath.Join(out, "FIXTURE.qvnotebook", "B59AC519-2A2C-4EC8-B701-E69F54F40A85.qvnote", "resources", "1C3392AA-54E7-4EA3-A129-1C20F208B029.jpg")
+	if info, err := os.Stat(resource); err != nil || info.Size() == 0 {
+		t.Errorf("quiver merge should copy the resources")
+	}
+
+	// an input cannot be the output
+	if code, _ := run("merge", "-f", fixture, out, fixture, out); code != cli.ExitError {
+		t.Errorf("quiver merge into an input: exit status %v; want %v", code, cli.ExitError)
+	}
+	if info, err := os.Stat(resource); err != nil || info.Size() == 0 {
+		t.Errorf("quiver merge into an input should leave it untouched")
+	}
+
+	// the elements removed by the merge do not linger in the replaced output
+	stale := filepath.Join(out, "STALE.qvnotebook")
+	if err := os.Mkdir(stale, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if code, o := run("merge", "-f", fixture, fixture, fixture, out); code != cli.ExitOK {
+		t.Fatalf("quiver merge -f: exit status %v\n%v", code, o)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("quiver merge -f should replace the output library")
+	}
+}
+
+func TestRunOptions(t *testing.T) {
+	lib := writeFixture(t)
+	trash := filepath.Join(lib, quiver.TrashUUID+".qvnotebook")
+	if err := os.Mkdir(trash, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(trash, "meta.json"), []byte(`{"name": "Trash", "uuid": "Trash"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the options of a run (like the Trash always included by diff) do not leak into the next ones
+	if code, o := run("diff", lib, lib); code != cli.ExitOK {
+		t.Fatalf("quiver diff = %v, %q", code, o)
+	}
+	if _, o := run("ls", lib); strings.Contains(o, "Trash/") {
+		t.Errorf("quiver ls = %q; the Trash should be skipped", o)
+	}
+	if _, o := run("ls", "-include-trash", lib); !strings.Contains(o, "Trash/ (Trash)") {
+		t.Errorf("quiver ls -include-trash = %q; want the Trash", o)
+	}
+}
+
+func TestDiffNotebookWithoutMetadata(t *testing.T) {
+	var out bytes.Buffer
+	cli.SetOutput(&out)
+	note := &quiver.Note{NoteMetadata: &quiver.NoteMetadata{UUID: "N", Title: "Note"}}
+
+	// a notebook without meta.json has no name
+	cli.PrintNoteDiff(&quiver.NoteDiff{UUID: "N", New: note, NewNotebook: &quiver.Notebook{}}, false)
+	cli.PrintNoteDiff(&quiver.NoteDiff{UUID: "N", Old: note, OldNotebook: &quiver.Notebook{}}, false)
+	want := "+ note \"Note\" in \"\" (N)\n- note \"Note\" in \"\" (N)\n"
+	if out.String() != want {
+		t.Errorf("output = %q; want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/ushu/quiver"
+)
+
+var diffCommand = &command{
+	name:    "diff",
+	args:    "OLD_LIBRARY NEW_LIBRARY",
+	summary: "Show the changes between two libraries",
+	help:    diffHelp,
+	flags: func(fs *flag.FlagSet, opts *options) {
+		fs.BoolVar(&opts.diffStat, "stat", false, "only list the changed notebooks and notes")
+	},
+	run: runDiff,
+}
+
+// The help about the output
+const diffHelp = `Notebooks and notes are matched by UUID. Each change starts with a marker:
+"+" for added elements, "-" for removed ones, and "~" for modified ones.
+
+The exit status is 0 when the libraries hold the same notes, and 1 otherwise.`
+
+func runDiff(fs *flag.FlagSet, opts *options) error {
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+
+	// the whole libraries are compared, including the Trash
+	opts.includeTrash = true
+	a, err := loadLibrary(fs.Arg(0), quiver.SkipResources, opts)
+	if err != nil {
+		return err
+	}
+	b, err := loadLibrary(fs.Arg(1), quiver.SkipResources, opts)
+	if err != nil {
+		return err
+	}
+
+	d := quiver.Diff(a, b)
+	for _, nd := range d.Notebooks {
+		printNotebookDiff(a, b, nd)
+	}
+	for _, nd := range d.Notes {
+		printNoteDiff(nd, opts.diffStat)
+	}
+
+	if !d.Empty() {
+		return exitCode(ExitFound)
+	}
+	return nil
+}
+
+func printNotebookDiff(a, b *quiver.Library, d *quiver.NotebookDiff) {
+	switch {
+	case d.Added():
+		fmt.Fprintf(stdout, "+ notebook %q (%v)\n", d.New.Name, d.UUID)
+	case d.Removed():
+		fmt.Fprintf(stdout, "- notebook %q (%v)\n", d.Old.Name, d.UUID)
+	default:
+		fmt.Fprintf(stdout, "~ notebook %q (%v)\n", d.New.Name, d.UUID)
+		if d.Renamed {
+			fmt.Fprintf(stdout, "    renamed from %q\n", d.Old.Name)
+		}
+		if d.Moved {
+			fmt.Fprintf(stdout, "    moved from %v to %v\n", notebookName(a, d.OldParent), notebookName(b, d.NewParent))
+		}
+	}
+}
+
+// printNoteDiff prints the changes of the note, without the lines of the cells when stat is set.
+func printNoteDiff(d *quiver.NoteDiff, stat bool) {
+	switch {
+	case d.Added():
+		fmt.Fprintf(stdout, "+ note %q in %q (%v)\n", d.New.Title, notebookLabel(d.NewNotebook), d.UUID)
+		return
+	case d.Removed():
+		fmt.Fprintf(stdout, "- note %q in %q (%v)\n", d.Old.Title, notebookLabel(d.OldNotebook), d.UUID)
+		return
+	}
+
+	fmt.Fprintf(stdout, "~ note %q (%v)\n", d.New.Title, d.UUID)
+	if d.Renamed {
+		fmt.Fprintf(stdout, "    renamed from %q\n", d.Old.Title)
+	}
+	if d.Moved {
+		fmt.Fprintf(stdout, "    moved from %q to %q\n", notebookLabel(d.OldNotebook), notebookLabel(d.NewNotebook))
+	}
+	if len(d.AddedTags) > 0 || len(d.RemovedTags) > 0 {
+		var tags []string
+		for _, t := range d.AddedTags {
+			tags = append(tags, "+"+t)
+		}
+		for _, t := range d.RemovedTags {
+			tags = append(tags, "-"+t)
+		}
+		fmt.Fprintf(stdout, "    tags: %v\n", strings.Join(tags, " "))
+	}
+	for _, c := range d.Cells {
+		index, cell := c.NewIndex, c.New
+		if c.Change == quiver.CellDeleted {
+			index, cell = c.OldIndex, c.Old
+		}
+		fmt.Fprintf(stdout, "    cell %v %v (%v)\n", index+1, c.Change, cell.Type)
+		if stat {
+			continue
+		}
+		for _, l := range c.Lines {
+			if l.Op != quiver.LineKept || c.Change == quiver.CellModified {
+				fmt.Fprintf(stdout, "      %v\n", l)
+			}
+		}
+	}
+}
+
+// notebookName returns the quoted name of the notebook with the given UUID, or "the root".
+func notebookName(lib *quiver.Library, uuid string) string {
+	if uuid == "" {
+		return "the root"
+	}
+	if nb := lib.NotebookByUUID(uuid); nb != nil {
+		return fmt.Sprintf("%q", nb.Name)
+	}
+	return uuid
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ushu/quiver"
+)
+
+var exportCommand = &command{
+	name:        "export",
+	summary:     "Export the notes of a library to other formats",
+	subcommands: []*command{exportJSONCommand, exportMarkdownCommand},
+}
+
+var exportJSONCommand = &command{
+	name:    "json",
+	args:    "QUIVER_LIBRARY [OUTPUT_FILE]",
+	summary: "Export a library as a single JSON bundle",
+	help:    exportJSONHelp,
+	globals: libraryOption | filterOptions,
+	flags: func(fs *flag.FlagSet, opts *options) {
+		fs.BoolVar(&opts.jsonResources, "res", false, "include the contents of the resources as data URIs")
+	},
+	run: runExportJSON,
+}
+
+// The help about the JSON export
+const exportJSONHelp = `The bundle is written to OUTPUT_FILE, or to the standard output when it is missing or "-".
+It can be turned back into a library with quiver_from_json (see quiver.EncodeBundle for the format).`
+
+func runExportJSON(fs *flag.FlagSet, opts *options) error {
+	path, args, err := libraryArgs(fs, opts)
+	if err != nil || len(args) > 1 {
+		return errUsage
+	}
+
+	mode := quiver.SkipResources
+	if opts.jsonResources {
+		mode = quiver.LoadResources
+	}
+	library, err := loadLibrary(path, mode, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 || args[0] == "-" {
+		return quiver.EncodeBundle(stdout, library)
+	}
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	err = quiver.EncodeBundle(f, library)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+var exportMarkdownCommand = &command{
+	name:    "markdown",
+	args:    "QUIVER_LIBRARY OUTPUT_DIRECTORY",
+	summary: "Export the notes of a library as a tree of Markdown files",
+	help:    exportMarkdownHelp,
+	globals: libraryOption | filterOptions,
+	flags: func(fs *flag.FlagSet, opts *options) {
+		fs.BoolVar(&opts.markdown.html, "html", false, "keep the HTML of text cells instead of converting it to Markdown")
+		fs.BoolVar(&opts.markdown.full, "full", false, "rewrite all the notes, even the ones unchanged since the previous run")
+		fs.BoolVar(&opts.markdown.git, "git", false, "commit each changed note into the git repository of the output directory")
+		fs.BoolVar(&opts.markdown.frontMatter, "front-matter", false, "start each note with its metadata as YAML front matter, and its title")
+	},
+	run: runExportMarkdown,
+}
+
+// markdownOptions are the options of the Markdown export.
+type markdownOptions struct {
+	// Keep the HTML of text cells as-is.
+	html bool
+	// Rewrite all the notes, instead of only the ones changed since the previous run.
+	full bool
+	// Commit each changed note into the git repository of the output directory.
+	git bool
+	// Start each note with its metadata and title.
+	frontMatter bool
+}
+
+// The help about the Markdown export
+const exportMarkdownHelp = `Each notebook is exported as a directory, and each note as a Markdown file named after its title.
+
+The exported files are listed in a manifest in the output directory (` + ManifestName + `), so that later
+runs only rewrite the changed notes, move the renamed ones, and delete the files of the removed notes.
+Outputs written by older versions have no manifest: the files of the notes removed before the upgrade are
+never deleted, so empty the output directory (except for .git) once before the first run.
+
+With -front-matter, each note starts with a YAML front matter block holding its UUID, title, notebook (as a path),
+tags and dates, followed by its title as a heading.
+
+With -git, the output directory should be in a git repository without staged changes: each changed note is then
+committed on its own, dated from its last update.`
+
+func runExportMarkdown(fs *flag.FlagSet, opts *options) error {
+	inPath, args, err := libraryArgs(fs, opts)
+	if err != nil || len(args) != 1 {
+		return errUsage
+	}
+
+	// (resources are only listed here, and copied over when writing the output)
+	library, err := loadLibrary(inPath, quiver.LazyResources, opts)
+	if err != nil {
+		return err
+	}
+
+	outPath := filepath.Clean(args[0])
+	if opts.markdown.git {
+		// (the output directory may be a new directory of the repository)
+		if err = EnsureDirectory(outPath); err != nil {
+			return err
+		}
+		if err = checkGitRepository(outPath); err != nil {
+			return err
+		}
+	}
+
+	index, err := notesIndex(outPath, library)
+	if err != nil {
+		return err
+	}
+
+	// output to the provided directory
+	stats, manifest, changes, err := writeLibrary(outPath, library, index, opts.markdown)
+	if err != nil {
+		return err
+	}
+	if opts.markdown.git {
+		commits, err := commitChanges(outPath, manifest, changes)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Created %d commits\n", commits)
+	}
+
+	fmt.Fprintf(stdout, "Done converting %q to %q (%v)\n", inPath, outPath, stats)
+	return nil
+}
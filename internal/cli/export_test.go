@@ -0,0 +1,11 @@
+package cli
+
+import "io"
+
+// SetOutput redirects the output of the commands.
+func SetOutput(w io.Writer) {
+	stdout, stderr = w, w
+}
+
+// PrintNoteDiff prints the changes of a note, like the diff command.
+var PrintNoteDiff = printNoteDiff
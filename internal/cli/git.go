@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// changeKind tells how an exported note changed
+type changeKind string
+
+// The changes of notes, as used in commit messages
+const (
+	noteAdded   changeKind = "Add"
+	noteUpdated changeKind = "Update"
+	noteMoved   changeKind = "Move"
+	noteDeleted changeKind = "Delete"
+)
+
+// noteChange is a note written, moved or deleted by an export
+type noteChange struct {
+	kind changeKind
+	// the new entry of the note in the manifest, or the previous one for deleted notes
+	entry *ManifestEntry
+	// the date of the change: the last update of the note, or the time of the export when unknown
+	date time.Time
+	// the files touched by the change, relative to the output directory (with slashes)
+	files []string
+}
+
+// message returns the commit message of the change.
+func (c *noteChange) message() string {
+	prep := "in"
+	switch c.kind {
+	case noteAdded, noteMoved:
+		prep = "to"
+	case noteDeleted:
+		prep = "from"
+	}
+	return fmt.Sprintf("%v %q %v %q", c.kind, c.entry.Title, prep, c.entry.Notebook)
+}
+
+// checkGitRepository makes sure that outPath is in a git repository, without staged changes.
+func checkGitRepository(outPath string) error {
+	if _, err := git(outPath, nil, "rev-parse", "--show-toplevel"); err != nil {
+		return errors.Wrapf(err, "%q is not in a git repository", outPath)
+	}
+	// the commits should only hold the changes of the notes
+	if _, err := git(outPath, nil, "diff", "--cached", "--quiet"); err != nil {
+		return errors.Errorf("the git repository of %q has staged changes, commit them first", outPath)
+	}
+	return nil
+}
+
+// commitChanges replays the changes of the notes into git, with one commit per note, dated from its last update.
+// The notes are committed from the least to the most recently updated one, and then the manifest m is written and
+// committed: when a commit fails, the previous manifest is kept, and the next export finds the same changes again
+// (the ones already committed are skipped).
+func commitChanges(outPath string, m *Manifest, changes []*noteChange) (int, error) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		if !changes[i].date.Equal(changes[j].date) {
+			return changes[i].date.Before(changes[j].date)
+		}
+		return changes[i].entry.Path < changes[j].entry.Path
+	})
+	changes = append(changes, &noteChange{files: []string{ManifestName}, date: time.Now()})
+
+	commits := 0
+	for _, c := range changes {
+		if c.entry == nil {
+			// all the notes are committed
+			if err := m.Write(outPath); err != nil {
+				return commits, err
+			}
+		}
+
+		// stage the new files, and the removed ones
+		var existing, removed []string
+		for _, f := range c.files {
+			if fileExists(filepath.Join(outPath, filepath.FromSlash(f))) {
+				existing = append(existing, f)
+			} else {
+				removed = append(removed, f)
+			}
+		}
+		if len(existing) > 0 {
+			if _, err := git(outPath, nil, append([]string{"add", "--"}, existing...)...); err != nil {
+				return commits, err
+			}
+		}
+		if len(removed) > 0 {
+			if _, err := git(outPath, nil, append([]string{"rm", "-q", "--cached", "--ignore-unmatch", "--"}, removed...)...); err != nil {
+				return commits, err
+			}
+		}
+		if _, err := git(outPath, nil, "diff", "--cached", "--quiet"); err == nil {
+			// already committed
+			continue
+		}
+
+		msg := "Update the export manifest"
+		if c.entry != nil {
+			msg = c.message()
+		}
+		date := c.date.UTC().Format(time.RFC3339)
+		env := []string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}
+		if _, err := git(outPath, env, "commit", "-q", "-m", msg); err != nil {
+			// unstage the files, so that the next export can commit them
+			if _, rerr := git(outPath, nil, append([]string{"reset", "-q", "--"}, c.files...)...); rerr != nil {
+				fmt.Fprintln(stderr, rerr)
+			}
+			return commits, err
+		}
+		commits++
+	}
+	return commits, nil
+}
+
+// git runs a git command in the given directory, and returns its output.
+func git(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.Errorf("git %v: %v", args[0], msg)
+		}
+		return "", errors.Wrapf(err, "git %v", args[0])
+	}
+	return string(out), nil
+}
@@ -0,0 +1,113 @@
+package cli_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ushu/quiver"
+	"github.com/ushu/quiver/internal/cli"
+)
+
+// gitRepository creates a new git repository, and returns its path
+func gitRepository(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	gitOutput(t, dir, "init", "-q")
+	gitOutput(t, dir, "config", "user.name", "Test")
+	gitOutput(t, dir, "config", "user.email", "test@example.com")
+	gitOutput(t, dir, "config", "commit.gpgsign", "false")
+	return dir
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+func TestExportMarkdownGit(t *testing.T) {
+	repo := gitRepository(t)
+	// the output is a new directory of the repository
+	out := filepath.Join(repo, "notes")
+
+	// the commits fail until the flag file is removed
+	flag := filepath.Join(t.TempDir(), "fail")
+	if err := os.WriteFile(flag, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	hook := fmt.Sprintf("#!/bin/sh\ntest ! -e %q\n", flag)
+	if err := os.WriteFile(filepath.Join(repo, ".git", "hooks", "pre-commit"), []byte(hook), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := run("export", "markdown", "-git", fixture, out); code != cli.ExitError {
+		t.Fatalf("quiver export markdown -git: exit status %v; want %v", code, cli.ExitError)
+	}
+	if _, err := os.Stat(filepath.Join(out, cli.ManifestName)); !os.IsNotExist(err) {
+		t.Errorf("the manifest should only be written once the notes are committed, got %v", err)
+	}
+
+	// the next export commits all the notes
+	if err := os.Remove(flag); err != nil {
+		t.Fatal(err)
+	}
+	if code, o := run("export", "markdown", "-git", fixture, out); code != cli.ExitOK || !strings.Contains(o, "Created 4 commits") {
+		t.Fatalf("quiver export markdown -git = %v, %q; want 4 commits", code, o)
+	}
+
+	lib, err := quiver.ReadLibrary(fixture, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, n := range lib.Notebooks[0].Notes {
+		want = append(want, fmt.Sprintf("%v Add %q to %q", time.Time(n.UpdatedAt).Unix(), n.Title, "Quiver Test"))
+	}
+	want = append(want, "Update the export manifest")
+
+	var got []string
+	for _, l := range strings.Split(strings.TrimSpace(gitOutput(t, repo, "log", "--reverse", "--format=%at %s")), "\n") {
+		if strings.HasSuffix(l, " Update the export manifest") {
+			// committed at the time of the export
+			l = "Update the export manifest"
+		}
+		got = append(got, l)
+	}
+	if !stringsContainAll(got, want) || len(got) != len(want) {
+		t.Errorf("git log = %q; want %q", got, want)
+	}
+	if status := gitOutput(t, repo, "status", "--porcelain"); status != "" {
+		t.Errorf("all the files should be committed, got %q", status)
+	}
+
+	// nothing is committed when nothing changed
+	if code, o := run("export", "markdown", "-git", fixture, out); code != cli.ExitOK || !strings.Contains(o, "Created 0 commits") {
+		t.Errorf("quiver export markdown -git = %v, %q; want no commits", code, o)
+	}
+}
+
+// stringsContainAll returns true when all the strings of want are in l
+func stringsContainAll(l []string, want []string) bool {
+	found := make(map[string]bool, len(l))
+	for _, s := range l {
+		found[s] = true
+	}
+	for _, s := range want {
+		if !found[s] {
+			return false
+		}
+	}
+	return true
+}
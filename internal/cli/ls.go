@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ushu/quiver"
+)
+
+var lsCommand = &command{
+	name:    "ls",
+	args:    "QUIVER_LIBRARY",
+	summary: "List the notebooks and notes of a library",
+	help:    lsHelp,
+	globals: libraryOption | filterOptions | formatOption,
+	run:     runLs,
+}
+
+// The help about the output
+const lsHelp = `The notebooks are listed following their hierarchy, each one followed by its notes.`
+
+// lsNotebook is a notebook, as printed in JSON
+type lsNotebook struct {
+	UUID   string   `json:"uuid"`
+	Name   string   `json:"name"`
+	Parent string   `json:"parent,omitempty"`
+	Notes  []lsNote `json:"notes"`
+}
+
+// lsNote is a note, as printed in JSON
+type lsNote struct {
+	UUID      string    `json:"uuid"`
+	Title     string    `json:"title"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func runLs(fs *flag.FlagSet, opts *options) error {
+	path, args, err := libraryArgs(fs, opts)
+	if err != nil || len(args) != 0 {
+		return errUsage
+	}
+
+	library, err := loadLibrary(path, quiver.SkipResources, opts)
+	if err != nil {
+		return err
+	}
+
+	list := []lsNotebook{}
+	err = library.WalkNotebooksHierarchy(func(nb *quiver.Notebook, parents []*quiver.Notebook) error {
+		if nb.NotebookMetadata == nil {
+			return nil
+		}
+		indent := strings.Repeat("  ", len(parents))
+		if opts.format == textFormat {
+			fmt.Fprintf(stdout, "%v%v/ (%v)\n", indent, nb.Name, nb.UUID)
+		}
+
+		l := lsNotebook{UUID: nb.UUID, Name: nb.Name, Notes: []lsNote{}}
+		if len(parents) > 0 {
+			l.Parent = parents[len(parents)-1].UUID
+		}
+		for _, n := range nb.Notes {
+			if n.NoteMetadata == nil {
+				continue
+			}
+			if opts.format == textFormat {
+				fmt.Fprintf(stdout, "%v  %v (%v)\n", indent, n.Title, n.UUID)
+			}
+			tags := n.Tags
+			if tags == nil {
+				tags = []string{}
+			}
+			l.Notes = append(l.Notes, lsNote{n.UUID, n.Title, tags, time.Time(n.CreatedAt), time.Time(n.UpdatedAt)})
+		}
+		list = append(list, l)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.format == jsonFormat {
+		return printJSON(list)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ushu/quiver"
+)
+
+// ManifestName is the name of the manifest file, in the output directory.
+const ManifestName = ".quiver_to_markdown.json"
+
+// ManifestVersion is the version of the manifest format: manifests with another version are ignored.
+const ManifestVersion = 1
+
+// Manifest records the files written by the previous run of the tool, so that the next one only rewrites the
+// changed notes, and only deletes its own files.
+type Manifest struct {
+	Version int `json:"version"`
+	// The exported notes, by UUID.
+	Notes map[string]*ManifestEntry `json:"notes"`
+}
+
+// ManifestEntry describes an exported note.
+type ManifestEntry struct {
+	// The path of the Markdown file, relative to the output directory (with slashes).
+	Path string `json:"path"`
+	// The title of the note, and the path of its notebook (the names of the notebooks from the root, joined by
+	// slashes).
+	Title    string `json:"title"`
+	Notebook string `json:"notebook"`
+	// The last modification time of the note.
+	UpdatedAt quiver.TimeStamp `json:"updated_at"`
+	// The SHA-256 hash of the Markdown file.
+	Hash string `json:"hash"`
+	// The paths of the resources of the note, relative to the output directory (with slashes).
+	Resources []string `json:"resources,omitempty"`
+}
+
+// NewManifest returns an empty manifest.
+func NewManifest() *Manifest {
+	return &Manifest{Version: ManifestVersion, Notes: make(map[string]*ManifestEntry)}
+}
+
+// ReadManifest loads the manifest of the output directory, or returns an empty one when there is none.
+func ReadManifest(outPath string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(outPath, ManifestName))
+	if os.IsNotExist(err) {
+		return NewManifest(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewManifest()
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Version != ManifestVersion || m.Notes == nil {
+		return NewManifest(), nil
+	}
+
+	// the files of the manifest are overwritten and deleted: they should not escape the output directory
+	for uuid, e := range m.Notes {
+		for _, f := range append([]string{e.Path}, e.Resources...) {
+			if !insideDirectory(outPath, f) {
+				return nil, fmt.Errorf("invalid manifest entry for note %v: %q is outside of the output directory", uuid, f)
+			}
+		}
+	}
+	return m, nil
+}
+
+// insideDirectory returns true when the slash-separated relative path p names a file inside dir.
+func insideDirectory(dir, p string) bool {
+	if p == "" || path.IsAbs(p) || filepath.IsAbs(filepath.FromSlash(p)) {
+		return false
+	}
+	rel, err := filepath.Rel(dir, filepath.Join(dir, filepath.FromSlash(p)))
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Write saves the manifest into the output directory.
+func (m *Manifest) Write(outPath string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outPath, ManifestName), append(data, '\n'), 0644)
+}
+
+// Files returns the set of all the files listed in the manifest, relative to the output directory.
+func (m *Manifest) Files() map[string]bool {
+	files := make(map[string]bool)
+	for _, e := range m.Notes {
+		files[e.Path] = true
+		for _, r := range e.Resources {
+			files[r] = true
+		}
+	}
+	return files
+}
@@ -0,0 +1,414 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/ushu/quiver"
+	"github.com/ushu/quiver/richtext"
+)
+
+// PathElementReplacer
+//
+// Theorically, all characters are acceptable (https://en.wikipedia.org/wiki/HFS_Plus) in path elements,
+// in practise they can cause strange issues in Finder...
+var PathElementReplacer = strings.NewReplacer(
+	"/", "|",
+	":", "-",
+)
+
+// Rewrite language name from Quiver Code Cell conventions to Github Markdown ones
+var languageEquivalents = map[string]string{
+	"c_cpp": "c++",
+}
+
+// Index of notes by UUID -> new path
+type NotesIndex map[string]string
+
+// notesIndex maps the notes to their Markdown files, named after their titles in a tree of directories named after
+// the notebooks.
+func notesIndex(outPath string, library *quiver.Library) (NotesIndex, error) {
+	var index NotesIndex = make(map[string]string)
+	err := library.WalkNotebooksHierarchy(func(nb *quiver.Notebook, parents []*quiver.Notebook) error {
+		// build the notebook path
+		pe := make([]string, 0)
+		pe = append(pe, outPath)
+		for _, p := range parents {
+			pe = append(pe, CleanPathElement(p.Name))
+		}
+		// then rhe notebook and the file name
+		pe = append(pe, CleanPathElement(nb.Name))
+		nbp := filepath.Join(pe...)
+
+		for _, n := range nb.Notes {
+			if _, ok := index[n.UUID]; ok {
+				return errors.Errorf("There found two notes with UUID \"%s\", aborting...", n.UUID)
+			}
+			index[n.UUID] = filepath.Join(nbp, CleanPathElement(n.Title)+".md")
+		}
+
+		return nil
+	})
+	return index, err
+}
+
+// exportStats counts the files touched by an export
+type exportStats struct {
+	written, moved, unchanged, deleted int
+}
+
+func (s exportStats) String() string {
+	return fmt.Sprintf("%d written, %d moved, %d unchanged, %d deleted", s.written, s.moved, s.unchanged, s.deleted)
+}
+
+// writeLibrary exports the notes into outPath, only rewriting the notes changed since the previous export (as
+// recorded in the manifest), and deleting the files of the notes that are gone.
+// It returns the new manifest and the changed notes, in no particular order.
+//
+// With -git, the manifest is not written: it is only written once the changes are committed (see commitChanges), so
+// that the changes not committed yet are found again by the next export.
+func writeLibrary(outPath string, library *quiver.Library, index NotesIndex, opts markdownOptions) (exportStats, *Manifest, []*noteChange, error) {
+	var stats exportStats
+	err := EnsureDirectory(outPath)
+	if err != nil {
+		return stats, nil, nil, err
+	}
+	prev, err := ReadManifest(outPath)
+	if err != nil {
+		return stats, nil, nil, errors.Wrap(err, "reading the manifest")
+	}
+
+	m := NewManifest()
+	changes := make(map[string]*noteChange)
+	err = library.WalkNotebooksHierarchy(func(nb *quiver.Notebook, parents []*quiver.Notebook) error {
+		// the names of the notebooks, from the root
+		names := make([]string, 0, len(parents)+1)
+		for _, p := range append(parents, nb) {
+			names = append(names, p.Name)
+		}
+		notebook := strings.Join(names, "/")
+
+		for _, note := range nb.Notes {
+			var p *ManifestEntry
+			if !opts.full {
+				p = prev.Notes[note.UUID]
+			}
+			e, c, err := writeNote(outPath, notebook, note, index, p, &stats, opts)
+			if err != nil {
+				return err
+			}
+			m.Notes[note.UUID] = e
+			if c != nil {
+				changes[note.UUID] = c
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// keep track of the files of the notes not exported yet, so that they can be deleted later
+		for uuid, e := range prev.Notes {
+			if _, ok := m.Notes[uuid]; !ok {
+				m.Notes[uuid] = e
+			}
+		}
+		if !opts.git {
+			if werr := m.Write(outPath); werr != nil {
+				fmt.Fprintln(stderr, werr)
+			}
+		}
+		return stats, nil, nil, err
+	}
+
+	// delete the files which are not part of the export anymore
+	files := m.Files()
+	for uuid, e := range prev.Notes {
+		for _, f := range append([]string{e.Path}, e.Resources...) {
+			if files[f] {
+				continue
+			}
+			// (a file already gone is still reported as a change, for its removal to be committed)
+			if p := filepath.Join(outPath, filepath.FromSlash(f)); fileExists(p) {
+				err = removeFile(outPath, p)
+				if err != nil {
+					return stats, nil, nil, err
+				}
+				stats.deleted++
+			}
+
+			c := changes[uuid]
+			if c == nil {
+				if n, ok := m.Notes[uuid]; ok {
+					// a resource was removed from the note
+					c = &noteChange{kind: noteUpdated, entry: n, date: time.Now()}
+				} else {
+					c = &noteChange{kind: noteDeleted, entry: e, date: time.Now()}
+				}
+				changes[uuid] = c
+			}
+			c.files = append(c.files, f)
+		}
+	}
+
+	list := make([]*noteChange, 0, len(changes))
+	for _, c := range changes {
+		list = append(list, c)
+	}
+	if opts.git {
+		return stats, m, list, nil
+	}
+	return stats, m, list, m.Write(outPath)
+}
+
+// writeNote exports the note and its resources, unless they did not change since prev, and returns the new entry of
+// the manifest, along with the change made (or nil when the note is unchanged).
+// notebook is the path of the notebook of the note: the names of its parents and its own name, joined by slashes.
+func writeNote(outPath string, notebook string, note *quiver.Note, index NotesIndex, prev *ManifestEntry, stats *exportStats, opts markdownOptions) (*ManifestEntry, *noteChange, error) {
+	p := index[note.UUID]
+	var buf bytes.Buffer
+	if opts.frontMatter {
+		if err := writeFrontMatter(&buf, note, notebook); err != nil {
+			return nil, nil, err
+		}
+	}
+	err := writeNoteMarkdown(&buf, p, note, index, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	e := &ManifestEntry{
+		Path:      relPath(outPath, p),
+		Title:     note.Title,
+		Notebook:  notebook,
+		UpdatedAt: note.UpdatedAt,
+		Hash:      hex.EncodeToString(sum[:]),
+	}
+
+	var c *noteChange
+	changed := func(kind changeKind, files ...string) {
+		if c == nil {
+			c = &noteChange{kind: kind, entry: e, date: time.Time(note.UpdatedAt)}
+			if prev == nil {
+				c.kind = noteAdded
+			}
+		}
+		c.files = append(c.files, files...)
+	}
+
+	// Write the note itself
+	err = EnsureDirectory(filepath.Dir(p))
+	if err != nil {
+		return nil, nil, err
+	}
+	prevPath := ""
+	if prev != nil {
+		prevPath = filepath.Join(outPath, filepath.FromSlash(prev.Path))
+	}
+	switch {
+	case prev != nil && prev.Hash == e.Hash && prevPath == p && fileExists(p):
+		stats.unchanged++
+	case prev != nil && prev.Hash == e.Hash && fileExists(prevPath) && !fileExists(p):
+		// renamed or moved
+		if err = os.Rename(prevPath, p); err != nil {
+			return nil, nil, err
+		}
+		stats.moved++
+		changed(noteMoved, prev.Path, e.Path)
+	default:
+		if err = os.WriteFile(p, buf.Bytes(), 0644); err != nil {
+			return nil, nil, err
+		}
+		stats.written++
+		changed(noteUpdated, e.Path)
+	}
+
+	// has resources ?
+	if len(note.Resources) == 0 {
+		return e, c, nil
+	}
+	rp := filepath.Join(filepath.Dir(p), "_resources")
+	err = EnsureDirectory(rp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the resources are only copied again when the note changed
+	prevResources := make(map[string]string)
+	if prev != nil && time.Time(prev.UpdatedAt).Equal(time.Time(note.UpdatedAt)) {
+		for _, r := range prev.Resources {
+			prevResources[path.Base(r)] = r
+		}
+	}
+	for _, r := range note.Resources {
+		op := filepath.Join(rp, r.Name)
+		e.Resources = append(e.Resources, relPath(outPath, op))
+
+		old, ok := prevResources[r.Name]
+		oldPath := filepath.Join(outPath, filepath.FromSlash(old))
+		switch {
+		case ok && oldPath == op && fileExists(op):
+			stats.unchanged++
+		case ok && fileExists(oldPath) && !fileExists(op):
+			if err = os.Rename(oldPath, op); err != nil {
+				return nil, nil, err
+			}
+			stats.moved++
+			changed(noteMoved, old, relPath(outPath, op))
+		default:
+			if err = r.WriteFile(op); err != nil {
+				return nil, nil, err
+			}
+			stats.written++
+			changed(noteUpdated, relPath(outPath, op))
+		}
+	}
+
+	return e, c, nil
+}
+
+// writeFrontMatter writes the metadata of the note as a YAML front matter block, followed by the title of the note:
+//
+//	---
+//	uuid: 73385592-0CAB-41E5-9045-AEC528C2915A
+//	title: "Tags"
+//	notebook: "Parent/Notebook"
+//	tags: ["retest", "tags"]
+//	created_at: 2017-09-18T10:40:10Z
+//	updated_at: 2017-09-18T10:40:18Z
+//	---
+//
+//	# Tags
+//
+// The strings are written as JSON strings, which are valid YAML double-quoted strings.
+func writeFrontMatter(out io.Writer, note *quiver.Note, notebook string) error {
+	tags := make([]string, len(note.Tags))
+	for i, t := range note.Tags {
+		tags[i] = yamlString(t)
+	}
+	_, err := fmt.Fprintf(out, "---\nuuid: %v\ntitle: %v\nnotebook: %v\ntags: [%v]\ncreated_at: %v\nupdated_at: %v\n---\n\n# %v\n\n",
+		note.UUID,
+		yamlString(note.Title),
+		yamlString(notebook),
+		strings.Join(tags, ", "),
+		time.Time(note.CreatedAt).UTC().Format(time.RFC3339),
+		time.Time(note.UpdatedAt).UTC().Format(time.RFC3339),
+		note.Title,
+	)
+	return err
+}
+
+// yamlString returns the double-quoted YAML version of the string.
+func yamlString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func writeNoteMarkdown(out io.Writer, p string, note *quiver.Note, index NotesIndex, opts markdownOptions) error {
+	var err error
+
+	for i, c := range note.Cells {
+		if i != 0 {
+			_, err = fmt.Fprintln(out)
+			if err != nil {
+				return err
+			}
+		}
+
+		// content to write: we replace all the data links to relative links
+		data := string(c.Data)
+		data = strings.Replace(data, "quiver-image-url/", "_resources/", -1)
+
+		if index != nil {
+			data = quiver.ReplaceNoteLinks(data, func(UUID string) string {
+				dir, _ := filepath.Rel(filepath.Dir(p), filepath.Dir(index[UUID]))
+				name := filepath.Base(index[UUID])
+				return dir + "/" + name
+			})
+		}
+
+		switch {
+		case c.IsCode():
+			// load language and (optionally) converts it to its Github Markdown equivalent
+			l := c.Language
+			if eq, ok := languageEquivalents[l]; ok {
+				l = eq
+			}
+			_, err = fmt.Fprintf(out, "```%v\n%v\n```", l, data)
+		case c.IsLatex():
+			_, err = fmt.Fprintf(out, "```latex\n%v\n```", data)
+		case c.IsMarkdown():
+			_, err = fmt.Fprintln(out, data)
+		case c.IsText() && opts.html:
+			_, err = fmt.Fprintln(out, data)
+		case c.IsText():
+			_, err = fmt.Fprintln(out, richtext.ToMarkdown(data))
+		case c.IsDiagram():
+			tool := "Sequence diagram, see https://bramp.github.io/js-sequence-diagrams"
+			if c.DiagramType == "flow" {
+				tool = "Flowchart diagram, see http://flowchart.js.org"
+			}
+			_, err = fmt.Fprintf(out, "```javascript\n// %v\n%v\n```", tool, data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeFile deletes a file written by a previous run, and then its parent directories until outPath, as long as they
+// are empty.
+func removeFile(outPath string, p string) error {
+	err := os.Remove(p)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for dir := filepath.Dir(p); dir != outPath && strings.HasPrefix(dir, outPath); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			// not empty
+			break
+		}
+	}
+	return nil
+}
+
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+// relPath returns the slash-separated path of p, relative to outPath.
+func relPath(outPath, p string) string {
+	rel, err := filepath.Rel(outPath, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func EnsureDirectory(outPath string) error {
+	err := os.MkdirAll(outPath, 0755)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+	return nil
+}
+
+func CleanPathElement(p string) string {
+	p = strings.TrimSpace(p)
+	p = PathElementReplacer.Replace(p)
+	return p
+}
@@ -0,0 +1,120 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ushu/quiver"
+	"github.com/ushu/quiver/internal/cli"
+)
+
+// writeFixture writes a copy of the fixture library, and returns its path
+func writeFixture(t *testing.T) string {
+	t.Helper()
+	lib, err := quiver.ReadLibrary(fixture, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "Quiver.qvlibrary")
+	if err = quiver.WriteLibrary(path, lib); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExportMarkdownIncremental(t *testing.T) {
+	lib := writeFixture(t)
+	notePath := func(uuid string) string {
+		return filepath.Join(lib, "FIXTURE.qvnotebook", uuid+".qvnote")
+	}
+	out := t.TempDir()
+	nb := filepath.Join(out, "Quiver Test")
+	export := func(want string, args ...string) {
+		t.Helper()
+		code, o := run(append(append([]string{"export", "markdown"}, args...), lib, out)...)
+		if code != cli.ExitOK || !strings.Contains(o, want) {
+			t.Errorf("quiver export markdown %v = %v, %q; want %q", strings.Join(args, " "), code, o, want)
+		}
+	}
+	exists := func(p string) bool {
+		_, err := os.Stat(p)
+		return err == nil
+	}
+
+	// the files of the user are kept
+	userFiles := []string{filepath.Join(out, "README.md"), filepath.Join(out, ".git", "HEAD")}
+	for _, p := range userFiles {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("user"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	export("(5 written, 0 moved, 0 unchanged, 0 deleted)")
+
+	// the resources are plain files
+	info, err := os.Stat(filepath.Join(nb, "_resources", "1C3392AA-54E7-4EA3-A129-1C20F208B029.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0111 != 0 {
+		t.Errorf("the mode of the resources is %v; want them not executable", info.Mode())
+	}
+
+	// a renamed note is moved
+	m, err := quiver.ReadNoteMetadata(filepath.Join(notePath("73385592-0CAB-41E5-9045-AEC528C2915A"), "meta.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Title = "Renamed"
+	if err = quiver.WriteNoteMetadata(filepath.Join(notePath(m.UUID), "meta.json"), m); err != nil {
+		t.Fatal(err)
+	}
+	export("(0 written, 1 moved, 4 unchanged, 0 deleted)")
+	if exists(filepath.Join(nb, "Tags.md")) || !exists(filepath.Join(nb, "Renamed.md")) {
+		t.Errorf("Tags.md should be moved to Renamed.md")
+	}
+
+	// the files of a removed note are deleted
+	if err = os.RemoveAll(notePath("B59AC519-2A2C-4EC8-B701-E69F54F40A85")); err != nil {
+		t.Fatal(err)
+	}
+	export("(0 written, 0 moved, 2 unchanged, 3 deleted)")
+	if exists(filepath.Join(nb, "Images, Files and Links.md")) || exists(filepath.Join(nb, "_resources")) {
+		t.Errorf("the files of the removed note should be deleted")
+	}
+	for _, p := range userFiles {
+		if !exists(p) {
+			t.Errorf("%v should be kept", p)
+		}
+	}
+
+	// everything is written again with -full
+	export("(2 written, 0 moved, 0 unchanged, 0 deleted)", "-full")
+}
+
+func TestExportMarkdownManifestOutside(t *testing.T) {
+	dir := t.TempDir()
+	victim := filepath.Join(dir, "victim.txt")
+	if err := os.WriteFile(victim, []byte("victim"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"version": 1, "notes": {"GONE": {"path": "../victim.txt", "hash": ""}}}`
+	if err := os.WriteFile(filepath.Join(out, ".quiver_to_markdown.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _ := run("export", "markdown", fixture, out); code != cli.ExitError {
+		t.Errorf("quiver export markdown: exit status %v; want %v", code, cli.ExitError)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("files outside of the output directory should never be deleted")
+	}
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ushu/quiver"
+)
+
+var mergeCommand = &command{
+	name:    "merge",
+	args:    "BASE_LIBRARY OUR_LIBRARY THEIR_LIBRARY OUTPUT_LIBRARY",
+	summary: "Merge two diverging copies of a library",
+	help:    mergeHelp,
+	flags: func(fs *flag.FlagSet, opts *options) {
+		fs.BoolVar(&opts.mergeForce, "f", false, "replace an existing output library")
+	},
+	run: runMerge,
+}
+
+// The help about the merge
+const mergeHelp = `The changes made in OUR_LIBRARY and THEIR_LIBRARY since BASE_LIBRARY, their common ancestor, are
+merged into OUTPUT_LIBRARY. Notebooks and notes are matched by UUID, tags are merged as sets, and cells one by one.
+
+When the same cells were changed on both sides, both versions are kept between conflict cells:
+"<<<<<<< ours", "=======" and ">>>>>>> theirs". For other conflicts, our side wins.
+
+The exit status is 0 when the merge is clean, and 1 when there are conflicts.`
+
+func runMerge(fs *flag.FlagSet, opts *options) error {
+	if fs.NArg() != 4 {
+		return errUsage
+	}
+	out := fs.Arg(3)
+	info, err := os.Stat(out)
+	if err == nil && !opts.mergeForce {
+		return fmt.Errorf("%v already exists, use -f to overwrite it", out)
+	}
+	if err == nil {
+		// the resources of the inputs are read lazily, from their files
+		for i := 0; i < 3; i++ {
+			if in, err := os.Stat(fs.Arg(i)); err == nil && os.SameFile(in, info) {
+				return fmt.Errorf("%v is also an input, merge into another library", out)
+			}
+		}
+	}
+
+	// the whole libraries are merged, including the Trash
+	opts.includeTrash = true
+	var libs [3]*quiver.Library
+	for i := range libs {
+		lib, err := loadLibrary(fs.Arg(i), quiver.LazyResources, opts)
+		if err != nil {
+			return err
+		}
+		libs[i] = lib
+	}
+
+	merged, conflicts := quiver.Merge(libs[0], libs[1], libs[2])
+	if err := writeMergedLibrary(out, merged); err != nil {
+		return err
+	}
+
+	for _, c := range conflicts {
+		fmt.Fprintf(stdout, "CONFLICT %v\n", c)
+	}
+	if len(conflicts) > 0 {
+		return exitCode(ExitFound)
+	}
+	return nil
+}
+
+// writeMergedLibrary writes the library into a new directory next to out, and then replaces out with it, so that the
+// notes and notebooks removed by the merge do not linger in an existing library.
+func writeMergedLibrary(out string, lib *quiver.Library) error {
+	if !strings.HasSuffix(out, ".qvlibrary") {
+		return fmt.Errorf("%w: %q should have .qvlibrary extension", quiver.ErrNotALibrary, out)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(out), ".merge-*.qvlibrary")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err = quiver.WriteLibrary(tmp, lib); err != nil {
+		return err
+	}
+	if err = os.RemoveAll(out); err != nil {
+		return err
+	}
+	return os.Rename(tmp, out)
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/ushu/quiver"
+	"github.com/ushu/quiver/search"
+)
+
+var searchCommand = &command{
+	name:    "search",
+	args:    "QUIVER_LIBRARY QUERY...",
+	summary: "Search the notes of a library",
+	help:    searchHelp,
+	globals: libraryOption | filterOptions | formatOption,
+	flags: func(fs *flag.FlagSet, opts *options) {
+		fs.IntVar(&opts.searchMax, "n", 20, "maximum number of results, 0 for all")
+	},
+	run: runSearch,
+}
+
+// The help about the query syntax
+const searchHelp = `The query is a list of words, all of which should match, mixed with filters:
+
+  tag:TAG             the note has the given tag
+  notebook:NAME       the note belongs to the notebook with the given name (or UUID)
+  type:TYPE           the note has a cell of the given type (code, text, markdown, latex, diagram)
+  lang:LANGUAGE       the note has a code cell in the given language
+  created:FROM..TO    the note was created between the two dates (YYYY-MM-DD, inclusive)
+  updated:FROM..TO    the note was updated between the two dates (YYYY-MM-DD, inclusive)
+
+The exit status is 1 when no note matches.`
+
+// searchResult is a result, as printed in JSON
+type searchResult struct {
+	UUID     string  `json:"uuid"`
+	Title    string  `json:"title"`
+	Notebook string  `json:"notebook"`
+	Score    float64 `json:"score"`
+	Snippet  string  `json:"snippet"`
+}
+
+func runSearch(fs *flag.FlagSet, opts *options) error {
+	path, args, err := libraryArgs(fs, opts)
+	if err != nil || len(args) == 0 {
+		return errUsage
+	}
+
+	q, err := search.ParseQuery(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+
+	library, err := loadLibrary(path, quiver.SkipResources, opts)
+	if err != nil {
+		return err
+	}
+
+	results := search.NewIndex(library).SearchQuery(q)
+	if opts.searchMax > 0 && len(results) > opts.searchMax {
+		results = results[:opts.searchMax]
+	}
+
+	if opts.format == jsonFormat {
+		list := make([]searchResult, len(results))
+		for i, r := range results {
+			list[i] = searchResult{r.Note.UUID, r.Note.Title, notebookLabel(r.Notebook), r.Score, r.Snippet}
+		}
+		err = printJSON(list)
+		if err != nil {
+			return err
+		}
+	} else {
+		for i, r := range results {
+			fmt.Fprintf(stdout, "%d. %v — %v (%v)\n", i+1, r.Note.Title, notebookLabel(r.Notebook), r.Note.UUID)
+			if r.Snippet != "" {
+				fmt.Fprintln(stdout, wrap(r.Snippet, 76, "   "))
+			}
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(stdout, "No matching notes")
+		}
+	}
+
+	if len(results) == 0 {
+		return exitCode(ExitFound)
+	}
+	return nil
+}
+
+// notebookLabel returns the name of the notebook, or "" when it has no metadata.
+func notebookLabel(nb *quiver.Notebook) string {
+	if nb == nil || nb.NotebookMetadata == nil {
+		return ""
+	}
+	return nb.Name
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ushu/quiver"
+)
+
+var showCommand = &command{
+	name:    "show",
+	args:    "QUIVER_LIBRARY NOTE",
+	summary: "Print a note",
+	help:    showHelp,
+	globals: libraryOption | filterOptions | formatOption,
+	run:     runShow,
+}
+
+// The help about the note selection
+const showHelp = `NOTE is the UUID or the title of the note. The note is printed as Markdown, or as the JSON of the note
+with -format json.
+
+The exit status is 1 when no note matches.`
+
+func runShow(fs *flag.FlagSet, opts *options) error {
+	path, args, err := libraryArgs(fs, opts)
+	if err != nil || len(args) != 1 {
+		return errUsage
+	}
+
+	library, err := loadLibrary(path, quiver.SkipResources, opts)
+	if err != nil {
+		return err
+	}
+
+	// find the note, by UUID first
+	note := library.NoteByUUID(args[0])
+	if note == nil {
+		var matches []*quiver.Note
+		for _, nb := range library.Notebooks {
+			for _, n := range nb.Notes {
+				if n.NoteMetadata != nil && strings.EqualFold(n.Title, args[0]) {
+					matches = append(matches, n)
+				}
+			}
+		}
+		switch len(matches) {
+		case 0:
+			fmt.Fprintln(stdout, "No matching note")
+			return exitCode(ExitFound)
+		case 1:
+			note = matches[0]
+		default:
+			uuids := make([]string, len(matches))
+			for i, n := range matches {
+				uuids[i] = n.UUID
+			}
+			return fmt.Errorf("several notes are titled %q, use one of their UUIDs: %v", args[0], strings.Join(uuids, ", "))
+		}
+	}
+
+	if opts.format == jsonFormat {
+		return printJSON(note)
+	}
+
+	fmt.Fprintf(stdout, "# %v\n\n", note.Title)
+	fmt.Fprintf(stdout, "Notebook: %v\n", notebookLabel(library.NotebookOf(note)))
+	if len(note.Tags) > 0 {
+		fmt.Fprintf(stdout, "Tags: %v\n", strings.Join(note.Tags, ", "))
+	}
+	fmt.Fprintf(stdout, "Created: %v\n", time.Time(note.CreatedAt).Format(time.RFC3339))
+	fmt.Fprintf(stdout, "Updated: %v\n", time.Time(note.UpdatedAt).Format(time.RFC3339))
+	if note.NoteContent == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err = writeNoteMarkdown(&buf, "", note, nil, markdownOptions{}); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout)
+	_, err = stdout.Write(buf.Bytes())
+	return err
+}
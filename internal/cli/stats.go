@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ushu/quiver"
+)
+
+var statsCommand = &command{
+	name:    "stats",
+	args:    "QUIVER_LIBRARY",
+	summary: "Print statistics about a library",
+	globals: libraryOption | filterOptions | formatOption,
+	run:     runStats,
+}
+
+// The cell types, in display order
+var cellTypes = []quiver.CellType{quiver.TextCell, quiver.CodeCell, quiver.MarkdownCell, quiver.LatexCell, quiver.DiagramCell}
+
+// libraryStats holds the statistics of a library, as printed in JSON
+type libraryStats struct {
+	Notebooks     int                     `json:"notebooks"`
+	Notes         int                     `json:"notes"`
+	Tags          int                     `json:"tags"`
+	Cells         map[quiver.CellType]int `json:"cells"`
+	Resources     int                     `json:"resources"`
+	ResourcesSize int64                   `json:"resources_size"`
+	// The creation of the oldest note, and the last update of a note.
+	FirstCreated time.Time `json:"first_created"`
+	LastUpdated  time.Time `json:"last_updated"`
+}
+
+func runStats(fs *flag.FlagSet, opts *options) error {
+	path, args, err := libraryArgs(fs, opts)
+	if err != nil || len(args) != 0 {
+		return errUsage
+	}
+
+	// resources are only listed, to get their sizes
+	library, err := loadLibrary(path, quiver.LazyResources, opts)
+	if err != nil {
+		return err
+	}
+
+	s := libraryStats{Cells: make(map[quiver.CellType]int)}
+	tags := make(map[string]bool)
+	for _, nb := range library.Notebooks {
+		s.Notebooks++
+		for _, n := range nb.Notes {
+			if n.NoteMetadata == nil {
+				continue
+			}
+			s.Notes++
+			for _, t := range n.Tags {
+				tags[t] = true
+			}
+			if created := time.Time(n.CreatedAt); s.FirstCreated.IsZero() || created.Before(s.FirstCreated) {
+				s.FirstCreated = created
+			}
+			if updated := time.Time(n.UpdatedAt); updated.After(s.LastUpdated) {
+				s.LastUpdated = updated
+			}
+			if n.NoteContent != nil {
+				for _, c := range n.Cells {
+					s.Cells[c.Type]++
+				}
+			}
+			for _, r := range n.Resources {
+				s.Resources++
+				s.ResourcesSize += r.Size
+			}
+		}
+	}
+	s.Tags = len(tags)
+
+	if opts.format == jsonFormat {
+		return printJSON(&s)
+	}
+
+	cells := 0
+	details := ""
+	for _, t := range cellTypes {
+		cells += s.Cells[t]
+		if details != "" {
+			details += ", "
+		}
+		details += fmt.Sprintf("%v %d", t, s.Cells[t])
+	}
+	fmt.Fprintf(stdout, "Notebooks:     %d\n", s.Notebooks)
+	fmt.Fprintf(stdout, "Notes:         %d\n", s.Notes)
+	fmt.Fprintf(stdout, "Tags:          %d\n", s.Tags)
+	fmt.Fprintf(stdout, "Cells:         %d (%v)\n", cells, details)
+	fmt.Fprintf(stdout, "Resources:     %d (%v)\n", s.Resources, formatSize(s.ResourcesSize))
+	if s.Notes > 0 {
+		fmt.Fprintf(stdout, "First created: %v\n", s.FirstCreated.Format(time.RFC3339))
+		fmt.Fprintf(stdout, "Last updated:  %v\n", s.LastUpdated.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// formatSize returns a human readable size.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,82 @@
+package quiver
+
+import (
+	"regexp"
+	"strings"
+)
+
+// noteURLRegexp matches the links to other notes, as found in the cells.
+var noteURLRegexp = regexp.MustCompile(`(quiver-note-url|quiver:///notes)/([0-9A-F]{8}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{12})`)
+
+// BrokenLink is a link to a note that cannot be found in the library.
+type BrokenLink struct {
+	// The note holding the link.
+	Note *Note
+	// The UUID of the missing note.
+	UUID string
+}
+
+// FindNoteLinks returns the UUIDs of all the notes linked from the given text, in order and without duplicates.
+// Both the "quiver-note-url/<UUID>" and "quiver:///notes/<UUID>" forms are recognized.
+func FindNoteLinks(s string) []string {
+	var uuids []string
+	seen := make(map[string]bool)
+	for _, m := range noteURLRegexp.FindAllStringSubmatch(s, -1) {
+		if !seen[m[2]] {
+			seen[m[2]] = true
+			uuids = append(uuids, m[2])
+		}
+	}
+	return uuids
+}
+
+// ReplaceNoteLinks replaces all the links to notes in the given text by the value returned by f for the linked
+// note UUID.
+func ReplaceNoteLinks(s string, f func(uuid string) string) string {
+	return noteURLRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		return f(m[strings.LastIndexByte(m, '/')+1:])
+	})
+}
+
+// OutgoingLinks returns the UUIDs of all the notes linked from the text, markdown and code cells of the note,
+// in order and without duplicates.
+func (n *Note) OutgoingLinks() []string {
+	if n.NoteContent == nil {
+		return nil
+	}
+
+	var uuids []string
+	seen := make(map[string]bool)
+	for _, c := range n.Cells {
+		if !c.IsText() && !c.IsMarkdown() && !c.IsCode() {
+			continue
+		}
+		for _, uuid := range FindNoteLinks(c.Data) {
+			if !seen[uuid] {
+				seen[uuid] = true
+				uuids = append(uuids, uuid)
+			}
+		}
+	}
+	return uuids
+}
+
+// Backlinks returns all the notes linking to the note with the given UUID, in library order.
+func (m *Library) Backlinks(uuid string) []*Note {
+	return m.lookup().backlinks[uuid]
+}
+
+// BrokenLinks returns all the links to notes that cannot be found in the library, in library order.
+func (m *Library) BrokenLinks() []BrokenLink {
+	var broken []BrokenLink
+	for _, nb := range m.Notebooks {
+		for _, n := range nb.Notes {
+			for _, uuid := range n.OutgoingLinks() {
+				if m.NoteByUUID(uuid) == nil {
+					broken = append(broken, BrokenLink{n, uuid})
+				}
+			}
+		}
+	}
+	return broken
+}
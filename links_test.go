@@ -0,0 +1,65 @@
+package quiver_test
+
+import (
+	"testing"
+
+	"github.com/ushu/quiver"
+)
+
+const (
+	linkedUUID = "9686AA1A-A5E9-41FF-9260-C3E0D0E9D4CB"
+	otherUUID  = "EDFC03DD-4E78-4405-A560-4A902FCE4312"
+)
+
+func TestFindNoteLinks(t *testing.T) {
+	t.Parallel()
+	s := `<a href="quiver-note-url/` + linkedUUID + `">A</a> [B](quiver:///notes/` + otherUUID + `) ` + linkedUUID
+
+	links := quiver.FindNoteLinks(s)
+	want := []string{linkedUUID, otherUUID}
+	if !stringSliceEqual(links, want) {
+		t.Errorf("FindNoteLinks(s) = %q; want %q", links, want)
+	}
+
+	replaced := quiver.ReplaceNoteLinks(s, func(uuid string) string { return uuid[:4] })
+	const wantReplaced = `<a href="9686">A</a> [B](EDFC) ` + linkedUUID
+	if replaced != wantReplaced {
+		t.Errorf("ReplaceNoteLinks(s) = %q; want %q", replaced, wantReplaced)
+	}
+}
+
+func TestLibraryLinks(t *testing.T) {
+	t.Parallel()
+	source := &quiver.Note{
+		NoteMetadata: &quiver.NoteMetadata{UUID: "SOURCE"},
+		NoteContent: &quiver.NoteContent{Cells: []*quiver.Cell{
+			{Type: quiver.TextCell, Data: `<a href="quiver-note-url/` + linkedUUID + `">Linked</a>`},
+			{Type: quiver.MarkdownCell, Data: `[Missing](quiver:///notes/` + otherUUID + `)`},
+			// links are not followed in LaTeX cells
+			{Type: quiver.LatexCell, Data: `quiver-note-url/` + linkedUUID},
+		}},
+	}
+	target := &quiver.Note{
+		NoteMetadata: &quiver.NoteMetadata{UUID: linkedUUID},
+		NoteContent:  &quiver.NoteContent{},
+	}
+	lib := &quiver.Library{Notebooks: []*quiver.Notebook{{
+		NotebookMetadata: &quiver.NotebookMetadata{UUID: "NB"},
+		Notes:            []*quiver.Note{source, target},
+	}}}
+
+	links := source.OutgoingLinks()
+	if want := []string{linkedUUID, otherUUID}; !stringSliceEqual(links, want) {
+		t.Errorf("source.OutgoingLinks() = %q; want %q", links, want)
+	}
+
+	backlinks := lib.Backlinks(linkedUUID)
+	if len(backlinks) != 1 || backlinks[0] != source {
+		t.Errorf("lib.Backlinks(%q) = %v; want [source]", linkedUUID, backlinks)
+	}
+
+	broken := lib.BrokenLinks()
+	if len(broken) != 1 || broken[0].Note != source || broken[0].UUID != otherUUID {
+		t.Errorf("lib.BrokenLinks() = %v; want a single link to %q", broken, otherUUID)
+	}
+}
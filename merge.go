@@ -0,0 +1,414 @@
+package quiver
+
+import (
+	"fmt"
+	"time"
+)
+
+// The data of the cells delimiting the conflicting cells of a merged note (see Merge).
+// The markers are MarkdownCell cells, like the ones of git: the cells of "ours" come first, then the cells of
+// "theirs".
+const (
+	ConflictStartMarker     = "<<<<<<< ours"
+	ConflictSeparatorMarker = "======="
+	ConflictEndMarker       = ">>>>>>> theirs"
+)
+
+// MergeConflict is a change made on both sides of a merge which could not be merged automatically.
+// In such cases, "ours" wins, except for the cells which are all kept, between conflict markers.
+type MergeConflict struct {
+	// The UUID of the note or notebook.
+	UUID string
+	// The title of the note, or the name of the notebook.
+	Title string
+	// What could not be merged.
+	Reason string
+}
+
+// String returns a human readable version of the conflict.
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("%v (%v): %v", c.Title, c.UUID, c.Reason)
+}
+
+// Merge merges the changes made in ours and theirs since base, typically two copies of a synced library:
+//   - notebooks and notes are matched by UUID, the changes (like additions, removals and moves) made on one side
+//     only are applied, as well as the ones made the same way on both sides,
+//   - tags are merged as sets: the tags added on either side are added, and the removed ones are removed,
+//   - the notebook hierarchy keeps all the notebooks of both sides, with the moves made on either side,
+//   - cells are merged one by one, and the conflicting ones are kept between conflict markers.
+//
+// base can be an empty library when the common ancestor is unknown. The merged library shares the unchanged
+// elements with ours and theirs.
+func Merge(base, ours, theirs *Library) (*Library, []MergeConflict) {
+	m := &merger{base: base, ours: ours, theirs: theirs}
+	lib := m.merge()
+	return lib, m.conflicts
+}
+
+type merger struct {
+	base, ours, theirs *Library
+	conflicts          []MergeConflict
+}
+
+func (m *merger) conflict(uuid, title, format string, args ...interface{}) {
+	m.conflicts = append(m.conflicts, MergeConflict{uuid, title, fmt.Sprintf(format, args...)})
+}
+
+func (m *merger) merge() *Library {
+	// the notebooks, in order
+	var uuids []string
+	seen := make(map[string]bool)
+	for _, lib := range []*Library{m.ours, m.theirs, m.base} {
+		for _, nb := range lib.Notebooks {
+			if nb.NotebookMetadata != nil && !seen[nb.UUID] {
+				seen[nb.UUID] = true
+				uuids = append(uuids, nb.UUID)
+			}
+		}
+	}
+
+	notebooks := make(map[string]*Notebook)
+	for _, uuid := range uuids {
+		b, o, t := m.base.NotebookByUUID(uuid), m.ours.NotebookByUUID(uuid), m.theirs.NotebookByUUID(uuid)
+		if nb := m.mergeNotebook(b, o, t); nb != nil {
+			notebooks[uuid] = nb
+		}
+	}
+
+	// the notes, in order
+	var noteUUIDs []string
+	seen = make(map[string]bool)
+	for _, lib := range []*Library{m.ours, m.theirs, m.base} {
+		for _, nb := range lib.Notebooks {
+			for _, n := range nb.Notes {
+				if n.NoteMetadata != nil && !seen[n.UUID] {
+					seen[n.UUID] = true
+					noteUUIDs = append(noteUUIDs, n.UUID)
+				}
+			}
+		}
+	}
+	for _, uuid := range noteUUIDs {
+		n, nbUUID := m.mergeNote(uuid)
+		if n == nil {
+			continue
+		}
+		nb := notebooks[nbUUID]
+		if nb == nil {
+			// the notebook was removed on one side, but the note still lives on the other one
+			nb = m.resurrectNotebook(nbUUID)
+			notebooks[nbUUID] = nb
+		}
+		nb.Notes = append(nb.Notes, n)
+	}
+
+	lib := &Library{}
+	for _, uuid := range uuids {
+		if nb, ok := notebooks[uuid]; ok {
+			lib.Notebooks = append(lib.Notebooks, nb)
+		}
+	}
+	lib.LibraryMetadata = m.mergeHierarchy(notebooks)
+	lib.Reindex()
+	return lib
+}
+
+// mergeNotebook merges the metadata of a notebook, and returns nil when the notebook was removed.
+// The notes of the notebook are merged separately.
+func (m *merger) mergeNotebook(b, o, t *Notebook) *Notebook {
+	if o == nil || t == nil {
+		kept := o
+		if kept == nil {
+			kept = t
+		}
+		switch {
+		case b == nil:
+			// added on one side
+		case kept == nil || kept.Name == b.Name:
+			// removed on one side (or both), and left untouched on the other one
+			return nil
+		default:
+			m.conflict(b.UUID, kept.Name, "the notebook was renamed on one side, and removed on the other one")
+		}
+		return &Notebook{NotebookMetadata: kept.NotebookMetadata, Notes: []*Note{}}
+	}
+
+	meta := *o.NotebookMetadata
+	baseName := ""
+	if b != nil {
+		baseName = b.Name
+	}
+	name, ok := merge3(baseName, o.Name, t.Name)
+	if !ok {
+		m.conflict(o.UUID, o.Name, "the notebook was renamed %q on our side, and %q on their side", o.Name, t.Name)
+	}
+	meta.Name = name
+	return &Notebook{NotebookMetadata: &meta, Notes: []*Note{}}
+}
+
+// resurrectNotebook brings back a removed notebook.
+func (m *merger) resurrectNotebook(uuid string) *Notebook {
+	for _, lib := range []*Library{m.ours, m.theirs, m.base} {
+		if nb := lib.NotebookByUUID(uuid); nb != nil {
+			m.conflict(uuid, nb.Name, "the notebook was removed, but still holds notes")
+			return &Notebook{NotebookMetadata: nb.NotebookMetadata, Notes: []*Note{}}
+		}
+	}
+	return &Notebook{NotebookMetadata: &NotebookMetadata{UUID: uuid}, Notes: []*Note{}}
+}
+
+// mergeNote merges the note with the given UUID, and returns it along with the UUID of its notebook, or nil when
+// the note was removed.
+func (m *merger) mergeNote(uuid string) (*Note, string) {
+	b, o, t := m.base.NoteByUUID(uuid), m.ours.NoteByUUID(uuid), m.theirs.NoteByUUID(uuid)
+	nbUUID := func(lib *Library, n *Note) string {
+		if nb := lib.NotebookOf(n); nb != nil && nb.NotebookMetadata != nil {
+			return nb.UUID
+		}
+		return ""
+	}
+
+	if o == nil || t == nil {
+		kept, lib := o, m.ours
+		if kept == nil {
+			kept, lib = t, m.theirs
+		}
+		switch {
+		case b == nil:
+			// added on one side
+		case kept == nil || (DiffNotes(b, kept).empty() && nbUUID(m.base, b) == nbUUID(lib, kept)):
+			// removed on one side (or both), and left untouched on the other one
+			return nil, ""
+		default:
+			m.conflict(uuid, kept.Title, "the note was modified on one side, and removed on the other one")
+		}
+		return kept, nbUUID(lib, kept)
+	}
+
+	if b == nil {
+		// added on both sides: merged against an empty note
+		b = &Note{NoteMetadata: &NoteMetadata{UUID: uuid}, NoteContent: &NoteContent{}}
+	}
+	nb, ok := merge3(nbUUID(m.base, b), nbUUID(m.ours, o), nbUUID(m.theirs, t))
+	if !ok {
+		m.conflict(uuid, o.Title, "the note was moved to different notebooks")
+	}
+
+	if DiffNotes(o, t).empty() {
+		// same contents on both sides
+		return o, nb
+	}
+	return m.mergeNoteContents(b, o, t), nb
+}
+
+// mergeNoteContents merges the metadata, cells and resources of a note.
+func (m *merger) mergeNoteContents(b, o, t *Note) *Note {
+	meta := *o.NoteMetadata
+	title, ok := merge3(b.Title, o.Title, t.Title)
+	if !ok {
+		m.conflict(o.UUID, o.Title, "the note was renamed %q on our side, and %q on their side", o.Title, t.Title)
+	}
+	meta.Title = title
+	meta.Tags = mergeSets(b.Tags, o.Tags, t.Tags)
+	if time.Time(t.UpdatedAt).After(time.Time(o.UpdatedAt)) {
+		meta.UpdatedAt = t.UpdatedAt
+	}
+
+	content := &NoteContent{}
+	if o.NoteContent != nil {
+		*content = *o.NoteContent
+	}
+	var conflict bool
+	content.Cells, conflict = mergeCells(noteCells(b), noteCells(o), noteCells(t))
+	if conflict {
+		m.conflict(o.UUID, title, "the same cells were modified on both sides")
+	}
+
+	// the resources are never removed: the unused ones can be pruned later
+	resources := append([]*NoteResource{}, o.Resources...)
+	names := make(map[string]bool, len(o.Resources))
+	for _, r := range o.Resources {
+		names[r.Name] = true
+	}
+	for _, r := range t.Resources {
+		if !names[r.Name] {
+			resources = append(resources, r)
+		}
+	}
+
+	return &Note{NoteMetadata: &meta, NoteContent: content, Resources: resources, fsys: o.fsys, path: o.path}
+}
+
+// mergeHierarchy rebuilds the hierarchy of the merged notebooks, applying the moves made on either side.
+func (m *merger) mergeHierarchy(notebooks map[string]*Notebook) *LibraryMetadata {
+	if m.ours.LibraryMetadata == nil && m.theirs.LibraryMetadata == nil {
+		return nil
+	}
+	pb, po, pt := hierarchyParents(m.base), hierarchyParents(m.ours), hierarchyParents(m.theirs)
+
+	// the notebooks of the hierarchy, in order
+	var order []string
+	seen := make(map[string]bool)
+	for _, lib := range []*Library{m.ours, m.theirs} {
+		if lib.LibraryMetadata == nil {
+			continue
+		}
+		var walk func(children []NotebookHierarchyInfo)
+		walk = func(children []NotebookHierarchyInfo) {
+			for _, c := range children {
+				if !seen[c.UUID] && notebooks[c.UUID] != nil {
+					seen[c.UUID] = true
+					order = append(order, c.UUID)
+				}
+				walk(c.Children)
+			}
+		}
+		walk(lib.Children)
+	}
+
+	// their parents
+	parents := make(map[string]string, len(order))
+	for _, uuid := range order {
+		o, inOurs := po[uuid]
+		t, inTheirs := pt[uuid]
+		var parent string
+		switch {
+		case inOurs && inTheirs:
+			var ok bool
+			parent, ok = merge3(pb[uuid], o, t)
+			if !ok {
+				m.conflict(uuid, notebooks[uuid].Name, "the notebook was moved to different places")
+			}
+		case inOurs:
+			parent = o
+		default:
+			parent = t
+		}
+		parents[uuid] = parent
+	}
+	for _, uuid := range order {
+		// the parent should still exist
+		if notebooks[parents[uuid]] == nil {
+			parents[uuid] = ""
+		}
+	}
+	for _, uuid := range order {
+		// and crossed moves should not create cycles
+		p := parents[uuid]
+		for i := 0; p != "" && p != uuid && i < len(order); i++ {
+			p = parents[p]
+		}
+		if p == uuid {
+			parents[uuid] = ""
+		}
+	}
+
+	var build func(parent string) []NotebookHierarchyInfo
+	build = func(parent string) []NotebookHierarchyInfo {
+		children := []NotebookHierarchyInfo{}
+		for _, uuid := range order {
+			if parents[uuid] == parent {
+				children = append(children, NotebookHierarchyInfo{UUID: uuid, Children: build(uuid)})
+			}
+		}
+		return children
+	}
+	meta := &LibraryMetadata{}
+	if m.ours.LibraryMetadata != nil {
+		*meta = *m.ours.LibraryMetadata
+	}
+	meta.Children = build("")
+	return meta
+}
+
+// mergeCells merges the cells changed on both sides (a diff3 at the cell level), and returns true when some
+// cells conflicted.
+func mergeCells(base, ours, theirs []*Cell) ([]*Cell, bool) {
+	// the base cells kept on each side
+	matchOurs := make(map[int]int)
+	for _, p := range lcs(len(base), len(ours), func(i, j int) bool { return cellsEqual(base[i], ours[j]) }) {
+		matchOurs[p[0]] = p[1]
+	}
+	matchTheirs := make(map[int]int)
+	for _, p := range lcs(len(base), len(theirs), func(i, j int) bool { return cellsEqual(base[i], theirs[j]) }) {
+		matchTheirs[p[0]] = p[1]
+	}
+
+	var merged []*Cell
+	conflict := false
+	i, j, k := 0, 0, 0
+	for b := 0; b <= len(base); b++ {
+		jo, inOurs := matchOurs[b]
+		kt, inTheirs := matchTheirs[b]
+		if b < len(base) && (!inOurs || !inTheirs) {
+			continue
+		}
+		if b == len(base) {
+			jo, kt = len(ours), len(theirs)
+		}
+
+		// the chunk changed since the previous stable cell
+		cb, co, ct := base[i:b], ours[j:jo], theirs[k:kt]
+		switch {
+		case cellSlicesEqual(co, cb):
+			merged = append(merged, ct...)
+		case cellSlicesEqual(ct, cb), cellSlicesEqual(co, ct):
+			merged = append(merged, co...)
+		default:
+			conflict = true
+			merged = append(merged, &Cell{Type: MarkdownCell, Data: ConflictStartMarker})
+			merged = append(merged, co...)
+			merged = append(merged, &Cell{Type: MarkdownCell, Data: ConflictSeparatorMarker})
+			merged = append(merged, ct...)
+			merged = append(merged, &Cell{Type: MarkdownCell, Data: ConflictEndMarker})
+		}
+
+		// then the stable cell
+		if b < len(base) {
+			merged = append(merged, ours[jo])
+		}
+		i, j, k = b+1, jo+1, kt+1
+	}
+	return merged, conflict
+}
+
+// merge3 merges a value changed on both sides, and returns false (with ours) when both sides changed it differently.
+func merge3(base, ours, theirs string) (string, bool) {
+	switch {
+	case ours == theirs, theirs == base:
+		return ours, true
+	case ours == base:
+		return theirs, true
+	default:
+		return ours, false
+	}
+}
+
+// mergeSets applies the additions and removals made on both sides.
+func mergeSets(base, ours, theirs []string) []string {
+	inBase, inOurs, inTheirs := stringSet(base), stringSet(ours), stringSet(theirs)
+	merged := []string{}
+	seen := make(map[string]bool)
+	for _, l := range [][]string{ours, theirs} {
+		for _, e := range l {
+			// kept on both sides, or added on one side
+			if !seen[e] && ((inOurs[e] && inTheirs[e]) || !inBase[e]) {
+				seen[e] = true
+				merged = append(merged, e)
+			}
+		}
+	}
+	return merged
+}
+
+func cellSlicesEqual(a, b []*Cell) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !cellsEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package quiver_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ushu/quiver"
+)
+
+func TestMergeOneSide(t *testing.T) {
+	t.Parallel()
+	flat := []quiver.NotebookHierarchyInfo{{UUID: "NB1"}}
+	base := buildLibrary(flat, map[string][]*quiver.Note{"NB1": {buildNote("N1", "Note", nil, "a")}})
+	changed := buildLibrary(flat, map[string][]*quiver.Note{"NB1": {buildNote("N1", "Renamed", []string{"t"}, "a", "b")}})
+
+	for _, libs := range [][3]*quiver.Library{{base, base, changed}, {base, changed, base}, {base, changed, changed}} {
+		merged, conflicts := quiver.Merge(libs[0], libs[1], libs[2])
+		if len(conflicts) != 0 {
+			t.Errorf("Merge() conflicts = %v; want none", conflicts)
+		}
+		if d := quiver.Diff(merged, changed); !d.Empty() {
+			t.Errorf("Merge() should keep the changes")
+		}
+	}
+}
+
+func TestMergeCells(t *testing.T) {
+	t.Parallel()
+	flat := []quiver.NotebookHierarchyInfo{{UUID: "NB1"}}
+	base := buildLibrary(flat, map[string][]*quiver.Note{"NB1": {buildNote("N1", "Note", nil, "a", "b", "c")}})
+	ours := buildLibrary(flat, map[string][]*quiver.Note{"NB1": {buildNote("N1", "Note", nil, "a", "b (ours)", "c")}})
+	theirs := buildLibrary(flat, map[string][]*quiver.Note{"NB1": {buildNote("N1", "Note", nil, "a", "b", "c", "d")}})
+
+	merged, conflicts := quiver.Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Errorf("Merge() conflicts = %v; want none", conflicts)
+	}
+	want := []string{"a", "b (ours)", "c", "d"}
+	if got := cellsData(merged.NoteByUUID("N1")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() cells = %q; want %q", got, want)
+	}
+
+	// the same cell modified on both sides
+	theirs = buildLibrary(flat, map[string][]*quiver.Note{"NB1": {buildNote("N1", "Note", nil, "a", "b (theirs)", "c")}})
+	merged, conflicts = quiver.Merge(base, ours, theirs)
+	if len(conflicts) != 1 || conflicts[0].UUID != "N1" {
+		t.Errorf("Merge() conflicts = %v; want a conflict on N1", conflicts)
+	}
+	want = []string{"a", quiver.ConflictStartMarker, "b (ours)", quiver.ConflictSeparatorMarker, "b (theirs)", quiver.ConflictEndMarker, "c"}
+	if got := cellsData(merged.NoteByUUID("N1")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() cells = %q; want %q", got, want)
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	t.Parallel()
+	flat := []quiver.NotebookHierarchyInfo{{UUID: "NB1"}}
+	base := buildLibrary(flat, map[string][]*quiver.Note{"NB1": {buildNote("N1", "Note", []string{"x", "y"})}})
+	ours := buildLibrary(flat, map[string][]*quiver.Note{"NB1": {buildNote("N1", "Note", []string{"x", "y", "o"})}})
+	theirs := buildLibrary(flat, map[string][]*quiver.Note{"NB1": {buildNote("N1", "Note", []string{"y", "t"})}})
+
+	merged, _ := quiver.Merge(base, ours, theirs)
+	want := []string{"y", "o", "t"}
+	if got := merged.NoteByUUID("N1").Tags; !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() tags = %q; want %q", got, want)
+	}
+}
+
+func TestMergeNotesAndNotebooks(t *testing.T) {
+	t.Parallel()
+	nested := []quiver.NotebookHierarchyInfo{{UUID: "NB1", Children: []quiver.NotebookHierarchyInfo{{UUID: "NB2"}}}}
+	base := buildLibrary(nested, map[string][]*quiver.Note{
+		"NB1": {buildNote("N1", "Unchanged", nil), buildNote("N2", "Modified", nil, "a")},
+		"NB2": {},
+	})
+	// ours moves NB2 to the root, removes N1 and modifies N2
+	ours := buildLibrary([]quiver.NotebookHierarchyInfo{{UUID: "NB1"}, {UUID: "NB2"}}, map[string][]*quiver.Note{
+		"NB1": {buildNote("N2", "Modified", nil, "b")},
+		"NB2": {},
+	})
+	// theirs adds NB3 under NB2, removes N2 and adds N3
+	theirs := buildLibrary([]quiver.NotebookHierarchyInfo{{UUID: "NB1", Children: []quiver.NotebookHierarchyInfo{
+		{UUID: "NB2", Children: []quiver.NotebookHierarchyInfo{{UUID: "NB3"}}},
+	}}}, map[string][]*quiver.Note{
+		"NB1": {buildNote("N1", "Unchanged", nil)},
+		"NB2": {},
+		"NB3": {buildNote("N3", "Added", nil)},
+	})
+
+	merged, conflicts := quiver.Merge(base, ours, theirs)
+	if merged.NoteByUUID("N1") != nil {
+		t.Errorf("N1 should be removed")
+	}
+	if merged.NoteByUUID("N2") == nil || len(conflicts) != 1 || conflicts[0].UUID != "N2" {
+		t.Errorf("N2 should be kept, with a conflict")
+	}
+	if n := merged.NoteByUUID("N3"); n == nil || merged.NotebookOf(n).UUID != "NB3" {
+		t.Errorf("N3 should be added to NB3")
+	}
+
+	want := []quiver.NotebookHierarchyInfo{
+		{UUID: "NB1", Children: []quiver.NotebookHierarchyInfo{}},
+		{UUID: "NB2", Children: []quiver.NotebookHierarchyInfo{{UUID: "NB3", Children: []quiver.NotebookHierarchyInfo{}}}},
+	}
+	if got := merged.Children; !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() hierarchy = %+v; want %+v", got, want)
+	}
+}
+
+func TestMergeWriteResources(t *testing.T) {
+	t.Parallel()
+	// a library written by the package, so that its files have the names WriteLibrary would use
+	src, err := quiver.ReadLibrary(fixturePath("Quiver.qvlibrary"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "Source.qvlibrary")
+	if err = quiver.WriteLibrary(path, src); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &quiver.ReadOptions{Resources: quiver.LazyResources}
+	lib, err := quiver.ReadLibraryWithOptions(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, conflicts := quiver.Merge(lib, lib, lib)
+	if len(conflicts) != 0 {
+		t.Errorf("Merge() conflicts = %v; want none", conflicts)
+	}
+
+	// the lazy resources are copied to a new library
+	out := filepath.Join(t.TempDir(), "Merged.qvlibrary")
+	if err = quiver.WriteLibrary(out, merged); err != nil {
+		t.Fatal(err)
+	}
+	want := src.NoteByUUID("B59AC519-2A2C-4EC8-B701-E69F54F40A85").Resources
+	got, err := quiver.ReadNote(filepath.Join(out, "FIXTURE.qvnotebook", "B59AC519-2A2C-4EC8-B701-E69F54F40A85.qvnote"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Resources) != len(want) {
+		t.Fatalf("len(Resources) = %v; want %v", len(got.Resources), len(want))
+	}
+	for i, r := range got.Resources {
+		if r.Name != want[i].Name || !bytes.Equal(r.Data, want[i].Data) {
+			t.Errorf("resource %v differs from the source", r.Name)
+		}
+	}
+
+	// and left as-is when written back onto their own files
+	if err = quiver.WriteLibrary(path, merged); err != nil {
+		t.Fatalf("WriteLibrary() onto the source of lazy resources: %v", err)
+	}
+	for _, r := range want {
+		data, err := os.ReadFile(filepath.Join(path, "FIXTURE.qvnotebook", "B59AC519-2A2C-4EC8-B701-E69F54F40A85.qvnote", "resources", r.Name))
+		if err != nil || !bytes.Equal(data, r.Data) {
+			t.Errorf("the source resource %v should be left untouched", r.Name)
+		}
+	}
+}
@@ -17,16 +17,20 @@ package quiver
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"mime"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -40,12 +44,24 @@ type Library struct {
 	*LibraryMetadata
 	// The list of Notebooks found inside the Library.
 	Notebooks []*Notebook `json:"notebooks"`
+	// The elements skipped while loading the Library in lenient mode.
+	Warnings []Warning `json:"-"`
+
+	// lookup tables, see Reindex
+	indexMu sync.Mutex
+	index   *libraryIndex
+
+	// where the library was loaded from
+	fsys fs.FS
+	path string
 }
 
 // LibraryMetadata represents the contents of a Quiver library metadata (meta.json) file.
 type LibraryMetadata struct {
 	// The root of the notebook hierarchy
 	Children []NotebookHierarchyInfo `json:"children"`
+	// The unknown fields of the file, kept as-is.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // A note in the Quote notebooks hierarchy
@@ -61,6 +77,10 @@ type Notebook struct {
 	*NotebookMetadata
 	// The list of Notes found inside the Notebook.
 	Notes []*Note `json:"notes"`
+
+	// where the notebook was loaded from
+	fsys fs.FS
+	path string
 }
 
 // NotebookMetadata represents the contents of a Quiver notebook (.qvnotebook) directory.
@@ -69,6 +89,8 @@ type NotebookMetadata struct {
 	Name string `json:"name"`
 	// The UUID of the Notebook.
 	UUID string `json:"uuid"`
+	// The unknown fields of the file, kept as-is.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // NoteContent represents the contents of a Quiver note (.qvnote) directory.
@@ -77,6 +99,10 @@ type Note struct {
 	*NoteContent
 	// The list of all Resources attached to this Note.
 	Resources []*NoteResource `json:"resources,omitempty"`
+
+	// where the note was loaded from
+	fsys fs.FS
+	path string
 }
 
 // NoteMetadata represents the contents of a Quiver note metadata (meta.json) file.
@@ -91,6 +117,8 @@ type NoteMetadata struct {
 	UpdatedAt TimeStamp `json:"updated_at"`
 	// The UUID of the Note.
 	UUID string `json:"uuid"`
+	// The unknown fields of the file, kept as-is.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // A timestamp in a Quiver note metadata file (meta.json).
@@ -98,8 +126,8 @@ type NoteMetadata struct {
 type TimeStamp time.Time
 
 // MarshalJSON marshals TimeStamp as an integer (seconds since Epoch).
-func (u *TimeStamp) MarshalJSON() ([]byte, error) {
-	secs := (*time.Time)(u).Unix()
+func (u TimeStamp) MarshalJSON() ([]byte, error) {
+	secs := time.Time(u).Unix()
 	return json.Marshal(secs)
 }
 
@@ -121,23 +149,52 @@ func (u *TimeStamp) UnmarshalJSON(data []byte) error {
 type NoteResource struct {
 	// The file name.
 	Name string `json:"name"`
-	// The file data as raw bytes.
-	// It serializes in JSON as a data URI.
+	// The file data as raw bytes, or nil when the resource is loaded lazily.
+	// It serializes in JSON as a base64 data URI.
 	Data []byte `json:"data"`
+	// The size of the file, in bytes.
+	Size int64 `json:"-"`
+	// The last modification time of the file.
+	ModTime time.Time `json:"-"`
+
+	// where to find the data when it is loaded lazily
+	fsys fs.FS
+	path string
+}
+
+// Open returns a stream to the data of the resource.
+// When the resource was loaded lazily, its data is read from the underlying file.
+func (n *NoteResource) Open() (io.ReadCloser, error) {
+	if n.Data == nil && n.fsys != nil {
+		return n.fsys.Open(n.path)
+	}
+	return ioutil.NopCloser(bytes.NewReader(n.Data)), nil
+}
+
+// ReadAll returns the data of the resource, reading it from the underlying file when loaded lazily.
+func (n *NoteResource) ReadAll() ([]byte, error) {
+	if n.Data == nil && n.fsys != nil {
+		return fs.ReadFile(n.fsys, n.path)
+	}
+	return n.Data, nil
 }
 
-// MarshalJSON marshals
+// MarshalJSON marshals NoteResource as a data URI.
 func (n *NoteResource) MarshalJSON() ([]byte, error) {
 	// Build a data uri for the resource
 	ext := filepath.Ext(n.Name)
 	mimeType := mime.TypeByExtension(ext)
-	b64 := base64.RawURLEncoding.EncodeToString(n.Data)
-	url := fmt.Sprintf("data:%v,%v", mimeType, b64)
+	data, err := n.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	b64 := base64.StdEncoding.EncodeToString(data)
+	url := fmt.Sprintf("data:%v;base64,%v", mimeType, b64)
 
 	// And then encode the uri as a JSON string
 	aux := struct {
-		Name string
-		Data string
+		Name string `json:"name"`
+		Data string `json:"data"`
 	}{
 		n.Name,
 		url,
@@ -148,10 +205,10 @@ func (n *NoteResource) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON unmarshals NoteResource from data:// url
 func (u *NoteResource) UnmarshalJSON(data []byte) error {
 	var aux struct {
-		Name string
-		URL  string
+		Name string `json:"name"`
+		Data string `json:"data"`
 	}
-	err := json.Unmarshal(data, aux)
+	err := json.Unmarshal(data, &aux)
 	if err != nil {
 		return err
 	}
@@ -160,22 +217,28 @@ func (u *NoteResource) UnmarshalJSON(data []byte) error {
 	u.Name = aux.Name
 
 	// Split data url
-	if !strings.HasPrefix(aux.URL, "data:") {
-		return fmt.Errorf("Invalid data URL %v", aux.URL)
+	if !strings.HasPrefix(aux.Data, "data:") {
+		return fmt.Errorf("Invalid data URL %q", aux.Data)
 	}
-	s := strings.SplitN(aux.URL, ",", 2)
+	s := strings.SplitN(aux.Data, ",", 2)
 	if len(s) != 2 {
-		return fmt.Errorf("data URL %v", aux.URL)
+		return fmt.Errorf("Invalid data URL %q", aux.Data)
 	}
 
 	// Decode the base64-encoded data
-	resData, err := base64.RawURLEncoding.DecodeString(s[1])
+	// (older versions of the package wrote unpadded, URL-safe base64 without the ";base64" marker)
+	enc := base64.RawURLEncoding
+	if strings.HasSuffix(s[0], ";base64") {
+		enc = base64.StdEncoding
+	}
+	resData, err := enc.DecodeString(s[1])
 	if err != nil {
 		return err
 	}
 
 	// Data found !
 	u.Data = resData
+	u.Size = int64(len(resData))
 
 	return nil
 }
@@ -183,10 +246,12 @@ func (u *NoteResource) UnmarshalJSON(data []byte) error {
 // NoteContent represents the contents of a Quiver not content (content.json) file.
 //
 // Beware: this structure does note contain the Title of the cell, since it is already held in the
-// NoteMetadata file.
+// NoteMetadata file (the one found in the file is kept in Extra).
 type NoteContent struct {
 	// The list of all cells in the note.
 	Cells []*Cell `json:"cells"`
+	// The unknown fields of the file, kept as-is.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // The type of a cell inside of a Quiver Note
@@ -211,6 +276,9 @@ type Cell struct {
 	DiagramType string `json:"diagramType,omitempty"`
 	// The data for the cell, aka. all the actual content.
 	Data string `json:"data"`
+	// The unknown fields of the cell, kept as-is.
+	// (since it holds a map, Cell values are not comparable with ==)
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // IsCode returns true if the Cell is of Type CodeCell.
@@ -241,82 +309,213 @@ func (c *Cell) IsDiagram() bool {
 // IsLibrary checks that the element at the given path is indeed a Quiver library, and
 // returns true if found or false with an error otherwise.
 func IsLibrary(path string) (bool, error) {
+	fsys, root := dirFS(path)
+	return IsLibraryFS(fsys, root)
+}
+
+// IsLibraryFS checks that the element at the given root in fsys is indeed a Quiver library, and
+// returns true if found or false with an error otherwise.
+func IsLibraryFS(fsys fs.FS, root string) (bool, error) {
 	// it should exist and be a library
-	stat, err := os.Stat(path)
+	stat, err := fs.Stat(fsys, root)
 	if err != nil {
 		return false, err
 	}
 	if !stat.IsDir() {
-		return false, errors.New("The Quiver Library should be a dictionary")
+		return false, fmt.Errorf("%w: %q should be a directory", ErrNotALibrary, elementPath(fsys, root))
 	}
 	// and end with .qvlibrary
 	if !strings.HasSuffix(stat.Name(), ".qvlibrary") {
-		return false, errors.New("The Quiver Library should have .qvlibrary extension")
+		return false, fmt.Errorf("%w: %q should have .qvlibrary extension", ErrNotALibrary, elementPath(fsys, root))
 	}
 
 	return true, nil
 }
 
+// ResourceMode tells how the resources of the notes are loaded.
+type ResourceMode int
+
+const (
+	// SkipResources does not load note resources at all.
+	SkipResources ResourceMode = iota
+	// LoadResources reads the data of all note resources in memory.
+	LoadResources
+	// LazyResources only lists note resources: their data is read when calling NoteResource.Open.
+	LazyResources
+)
+
+// ReadOptions holds the options used when loading libraries, notebooks and notes.
+// A nil *ReadOptions is valid and uses the default values.
+type ReadOptions struct {
+	// How the resources of the notes are loaded.
+	Resources ResourceMode
+	// The maximum number of notes loaded in parallel by ReadLibraryContext.
+	// When zero, runtime.GOMAXPROCS(0) is used.
+	Concurrency int
+	// When set, hidden entries and entries without the expected extension are ignored, and malformed notebooks
+	// and notes are skipped instead of failing the whole loading. Skipped elements are reported as Warnings.
+	Lenient bool
+	// When set, it is called for each Warning emitted in lenient mode.
+	OnWarning func(w Warning)
+	// When set, the Trash notebook is not loaded.
+	ExcludeTrash bool
+}
+
+func (o *ReadOptions) resources() ResourceMode {
+	if o == nil {
+		return SkipResources
+	}
+	return o.Resources
+}
+
+// excludes returns true when the notebook stored in the named directory should not be loaded.
+func (o *ReadOptions) excludes(name string) bool {
+	return o != nil && o.ExcludeTrash && name == TrashUUID+".qvnotebook"
+}
+
+// resourceOptions returns the options matching the loadResources parameter of the ReadXXX functions.
+func resourceOptions(loadResources bool) *ReadOptions {
+	if loadResources {
+		return &ReadOptions{Resources: LoadResources}
+	}
+	return nil
+}
+
 // ReadLibrary loads the Quiver library at the given path.
 // The loadResources parameter tells the function if note resources should be loaded too.
 func ReadLibrary(path string, loadResources bool) (*Library, error) {
-	_, err := IsLibrary(path)
+	fsys, root := dirFS(path)
+	return ReadLibraryFS(fsys, root, loadResources)
+}
+
+// ReadLibraryFS loads the Quiver library found at the given root in fsys.
+// The loadResources parameter tells the function if note resources should be loaded too.
+func ReadLibraryFS(fsys fs.FS, root string, loadResources bool) (*Library, error) {
+	return ReadLibraryFSWithOptions(fsys, root, resourceOptions(loadResources))
+}
+
+// ReadLibraryWithOptions loads the Quiver library at the given path, as configured by opts.
+func ReadLibraryWithOptions(path string, opts *ReadOptions) (*Library, error) {
+	fsys, root := dirFS(path)
+	return ReadLibraryFSWithOptions(fsys, root, opts)
+}
+
+// ReadLibraryFSWithOptions loads the Quiver library found at the given root in fsys, as configured by opts.
+func ReadLibraryFSWithOptions(fsys fs.FS, root string, opts *ReadOptions) (*Library, error) {
+	w := newWarnings(fsys, opts)
+	metadata, paths, err := readLibraryDir(fsys, root, opts, w)
 	if err != nil {
 		return nil, err
 	}
 
-	// list the files in the library (aka. the notes)
-	files, err := ioutil.ReadDir(path)
+	notebooks := make([]*Notebook, 0, len(paths))
+	for _, p := range paths {
+		n, err := readNotebook(fsys, p, opts, w)
+		if err != nil {
+			if w.skip(p, err) {
+				continue
+			}
+			return nil, err
+		}
+		notebooks = append(notebooks, n)
+	}
+
+	lib := &Library{LibraryMetadata: metadata, Notebooks: notebooks, Warnings: w.all(), fsys: fsys, path: root}
+	lib.Reindex()
+	return lib, nil
+}
+
+// readLibraryDir loads the metadata of the library found at the given root in fsys, and lists the paths of its
+// notebooks (but the ones excluded by opts).
+func readLibraryDir(fsys fs.FS, root string, opts *ReadOptions, w *warnings) (*LibraryMetadata, []string, error) {
+	_, err := IsLibraryFS(fsys, root)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// list the files in the library (aka. the notebooks)
+	files, err := fs.ReadDir(fsys, root)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var metadata *LibraryMetadata
-	notebooks := make([]*Notebook, 0, len(files))
+	paths := make([]string, 0, len(files))
 	for _, f := range files {
-		p := filepath.Join(path, f.Name())
+		p := path.Join(root, f.Name())
 
 		// ignore root meta.json
 		if f.Name() == "meta.json" {
-			metadata, err = ReadLibraryMetadata(p)
-			if err != nil {
-				return nil, err
+			metadata, err = ReadLibraryMetadataFS(fsys, p)
+			if err != nil && !w.skip(p, err) {
+				return nil, nil, err
 			}
-		} else {
+		} else if !w.ignore(p, f, ".qvnotebook") && !opts.excludes(f.Name()) {
 			// all other elements should be notebooks
-			n, err := ReadNotebook(p, loadResources)
-			if err != nil {
-				return nil, err
-			}
-			notebooks = append(notebooks, n)
+			paths = append(paths, p)
 		}
 	}
 
-	return &Library{metadata, notebooks}, nil
+	return metadata, paths, nil
 }
 
 // WalkNotebooksHierarchy returns all the notebooks in order, allowing to "explore" the internal hierarchy of the
 // Quiver library.
+//
+// Each loaded notebook is visited exactly once: the notebooks missing from the hierarchy (like the Inbox and the
+// Trash) are visited last, at the root. The UUIDs of the hierarchy that do not match any loaded notebook are
+// skipped.
 func (m *Library) WalkNotebooksHierarchy(f func(n *Notebook, parents []*Notebook) error) error {
+	// (the notebooks are looked up from m.Notebooks, which may have changed since the last Reindex)
 	notebooks := make(map[string]*Notebook, len(m.Notebooks))
 	for _, n := range m.Notebooks {
-		notebooks[n.UUID] = n
+		if n.NotebookMetadata == nil {
+			continue
+		}
+		if _, ok := notebooks[n.UUID]; !ok {
+			notebooks[n.UUID] = n
+		}
 	}
+	visited := make(map[*Notebook]bool, len(m.Notebooks))
 
+	var children []NotebookHierarchyInfo
+	if m.LibraryMetadata != nil {
+		children = m.LibraryMetadata.Children
+	}
 	parents := make([]string, 0)
-	for _, n := range m.LibraryMetadata.Children {
+	for _, n := range children {
 		err := walkNotebooksHierarchy(n, parents, func(c string, parents []string) error {
-			pp := make([]*Notebook, len(parents))
-			for i, p := range parents {
-				pp[i] = notebooks[p]
+			nb := notebooks[c]
+			if nb == nil || visited[nb] {
+				return nil
+			}
+			visited[nb] = true
+
+			pp := make([]*Notebook, 0, len(parents))
+			for _, p := range parents {
+				if pnb := notebooks[p]; pnb != nil {
+					pp = append(pp, pnb)
+				}
 			}
-			return f(notebooks[c], pp)
+			return f(nb, pp)
 		})
 		if err != nil {
 			return err
 		}
 	}
 
+	// then all the notebooks missing from the hierarchy
+	for _, nb := range m.Notebooks {
+		if visited[nb] {
+			continue
+		}
+		visited[nb] = true
+		err := f(nb, []*Notebook{})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -341,8 +540,14 @@ func walkNotebooksHierarchy(n NotebookHierarchyInfo, parents []string, f func(n
 
 // ReadLibraryMetadata loads the library "meta.json" at the given path.
 func ReadLibraryMetadata(path string) (*LibraryMetadata, error) {
+	fsys, name := dirFS(path)
+	return ReadLibraryMetadataFS(fsys, name)
+}
+
+// ReadLibraryMetadataFS loads the library "meta.json" with the given name in fsys.
+func ReadLibraryMetadataFS(fsys fs.FS, name string) (*LibraryMetadata, error) {
 	// find and read metadata file
-	mf, err := os.Open(path)
+	mf, err := fsys.Open(name)
 	if err != nil {
 		return nil, err
 	}
@@ -350,23 +555,31 @@ func ReadLibraryMetadata(path string) (*LibraryMetadata, error) {
 
 	// Read metadata
 	buf := bufio.NewReader(mf)
-	return ParseLibraryMetadata(buf)
+	m, err := ParseLibraryMetadata(buf)
+	return m, withPath(err, elementPath(fsys, name))
 }
 
 // IsNoteBook checks that the element at the given path is indeed a Quiver notebook, and
 // returns true if found or false with an error otherwise.
 func IsNotebook(path string) (bool, error) {
+	fsys, root := dirFS(path)
+	return IsNotebookFS(fsys, root)
+}
+
+// IsNotebookFS checks that the element at the given root in fsys is indeed a Quiver notebook, and
+// returns true if found or false with an error otherwise.
+func IsNotebookFS(fsys fs.FS, root string) (bool, error) {
 	// it should exist and be a directory
-	stat, err := os.Stat(path)
+	stat, err := fs.Stat(fsys, root)
 	if err != nil {
 		return false, err
 	}
 	if !stat.IsDir() {
-		return false, errors.New("A Quiver Notebook should be a directory")
+		return false, fmt.Errorf("%w: %q should be a directory", ErrNotANotebook, elementPath(fsys, root))
 	}
 	// and end with .qvnotebook
 	if !strings.HasSuffix(stat.Name(), ".qvnotebook") {
-		return false, errors.New("A Quiver Notebook should have .qvnotebook extension")
+		return false, fmt.Errorf("%w: %q should have .qvnotebook extension", ErrNotANotebook, elementPath(fsys, root))
 	}
 
 	return true, nil
@@ -375,56 +588,105 @@ func IsNotebook(path string) (bool, error) {
 // ReadNotebook loads the Quiver notebook in the given path.
 // The loadResources parameter tells the function if note resources should be loaded too.
 func ReadNotebook(path string, loadResources bool) (*Notebook, error) {
-	_, err := IsNotebook(path)
+	fsys, root := dirFS(path)
+	return ReadNotebookFS(fsys, root, loadResources)
+}
+
+// ReadNotebookFS loads the Quiver notebook found at the given root in fsys.
+// The loadResources parameter tells the function if note resources should be loaded too.
+func ReadNotebookFS(fsys fs.FS, root string, loadResources bool) (*Notebook, error) {
+	return ReadNotebookFSWithOptions(fsys, root, resourceOptions(loadResources))
+}
+
+// ReadNotebookWithOptions loads the Quiver notebook in the given path, as configured by opts.
+func ReadNotebookWithOptions(path string, opts *ReadOptions) (*Notebook, error) {
+	fsys, root := dirFS(path)
+	return ReadNotebookFSWithOptions(fsys, root, opts)
+}
+
+// ReadNotebookFSWithOptions loads the Quiver notebook found at the given root in fsys, as configured by opts.
+func ReadNotebookFSWithOptions(fsys fs.FS, root string, opts *ReadOptions) (*Notebook, error) {
+	return readNotebook(fsys, root, opts, newWarnings(fsys, opts))
+}
+
+func readNotebook(fsys fs.FS, root string, opts *ReadOptions, w *warnings) (*Notebook, error) {
+	metadata, paths, err := readNotebookDir(fsys, root, w)
 	if err != nil {
 		return nil, err
 	}
 
+	notes := make([]*Note, 0, len(paths))
+	for _, p := range paths {
+		n, err := ReadNoteFSWithOptions(fsys, p, opts)
+		if err != nil {
+			if w.skip(p, err) {
+				continue
+			}
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+
+	return &Notebook{NotebookMetadata: metadata, Notes: notes, fsys: fsys, path: root}, nil
+}
+
+// readNotebookDir loads the metadata of the notebook found at the given root in fsys, and lists the paths of its
+// notes.
+func readNotebookDir(fsys fs.FS, root string, w *warnings) (*NotebookMetadata, []string, error) {
+	_, err := IsNotebookFS(fsys, root)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// list the files in the notebook (aka. the notes)
-	files, err := ioutil.ReadDir(path)
+	files, err := fs.ReadDir(fsys, root)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var metadata *NotebookMetadata
-	var numNotes = 0
-	if len(files) > 1 {
-		numNotes = len(files) - 1
-	}
-	notes := make([]*Note, numNotes)
-	for i, f := range files {
-		p := filepath.Join(path, f.Name())
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		p := path.Join(root, f.Name())
 		if f.Name() == "meta.json" {
-			metadata, err = ReadNotebookMetadata(p)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			n, err := ReadNote(p, loadResources)
+			metadata, err = ReadNotebookMetadataFS(fsys, p)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
-			notes[i] = n
+		} else if !w.ignore(p, f, ".qvnote") {
+			paths = append(paths, p)
 		}
 	}
 
-	return &Notebook{metadata, notes}, nil
+	// a notebook without metadata is only partially synced
+	if metadata == nil && w.lenient() {
+		return nil, nil, fmt.Errorf("%w: %q should have a meta.json file", ErrNotANotebook, elementPath(fsys, root))
+	}
+
+	return metadata, paths, nil
 }
 
 // IsNote checks that the element at the given path is indeed a Quiver note, and
 // returns true if found or false with an error otherwise.
 func IsNote(path string) (bool, error) {
+	fsys, root := dirFS(path)
+	return IsNoteFS(fsys, root)
+}
+
+// IsNoteFS checks that the element at the given root in fsys is indeed a Quiver note, and
+// returns true if found or false with an error otherwise.
+func IsNoteFS(fsys fs.FS, root string) (bool, error) {
 	// it should exist and be a directory
-	stat, err := os.Stat(path)
+	stat, err := fs.Stat(fsys, root)
 	if err != nil {
 		return false, err
 	}
 	if !stat.IsDir() {
-		return false, errors.New("A Quiver Note should be a directory")
+		return false, fmt.Errorf("%w: %q should be a directory", ErrNotANote, elementPath(fsys, root))
 	}
 	// and end with .qvnote
 	if !strings.HasSuffix(stat.Name(), ".qvnote") {
-		return false, errors.New("A Quiver Note should have .qvnote extension")
+		return false, fmt.Errorf("%w: %q should have .qvnote extension", ErrNotANote, elementPath(fsys, root))
 	}
 
 	return true, nil
@@ -433,41 +695,70 @@ func IsNote(path string) (bool, error) {
 // ReadNote loads the Quiver note in the given path.
 // The loadResources parameter tells the function if note resources should be loaded too.
 func ReadNote(path string, loadResources bool) (*Note, error) {
-	_, err := IsNote(path)
+	fsys, root := dirFS(path)
+	return ReadNoteFS(fsys, root, loadResources)
+}
+
+// ReadNoteFS loads the Quiver note found at the given root in fsys.
+// The loadResources parameter tells the function if note resources should be loaded too.
+func ReadNoteFS(fsys fs.FS, root string, loadResources bool) (*Note, error) {
+	return ReadNoteFSWithOptions(fsys, root, resourceOptions(loadResources))
+}
+
+// ReadNoteWithOptions loads the Quiver note in the given path, as configured by opts.
+func ReadNoteWithOptions(path string, opts *ReadOptions) (*Note, error) {
+	fsys, root := dirFS(path)
+	return ReadNoteFSWithOptions(fsys, root, opts)
+}
+
+// ReadNoteFSWithOptions loads the Quiver note found at the given root in fsys, as configured by opts.
+func ReadNoteFSWithOptions(fsys fs.FS, root string, opts *ReadOptions) (*Note, error) {
+	_, err := IsNoteFS(fsys, root)
 	if err != nil {
 		return nil, err
 	}
 
 	// Read the metadata file
-	mp := filepath.Join(path, "meta.json")
-	m, err := ReadNoteMetadata(mp)
+	mp := path.Join(root, "meta.json")
+	m, err := ReadNoteMetadataFS(fsys, mp)
 	if err != nil {
 		return nil, err
 	}
 
 	// Read the content file
-	cp := filepath.Join(path, "content.json")
-	c, err := ReadNoteContent(cp)
+	cp := path.Join(root, "content.json")
+	c, err := ReadNoteContentFS(fsys, cp)
 	if err != nil {
 		return nil, err
 	}
 
 	var res []*NoteResource
-	if loadResources {
-		rp := filepath.Join(path, "resources")
-		res, err = ReadNoteResources(rp)
+	if mode := opts.resources(); mode != SkipResources {
+		rp := path.Join(root, "resources")
+		res, err = readNoteResources(fsys, rp, mode == LazyResources)
 		// we check for error but ignore not existing dir
-		if err != nil && !os.IsNotExist(err) {
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return nil, err
 		}
 	}
 
-	return &Note{m, c, res}, nil
+	return &Note{NoteMetadata: m, NoteContent: c, Resources: res, fsys: fsys, path: root}, nil
 }
 
 // ReadNoteResource loads the resource (any file actually) into a NoteResource instance.
 func ReadNoteResources(path string) ([]*NoteResource, error) {
-	stat, err := os.Stat(path)
+	fsys, root := dirFS(path)
+	return ReadNoteResourcesFS(fsys, root)
+}
+
+// ReadNoteResourcesFS loads the resources found in the root directory of fsys.
+func ReadNoteResourcesFS(fsys fs.FS, root string) ([]*NoteResource, error) {
+	return readNoteResources(fsys, root, false)
+}
+
+// readNoteResources lists the resources found in the root directory of fsys, and reads their data unless lazy is set.
+func readNoteResources(fsys fs.FS, root string, lazy bool) ([]*NoteResource, error) {
+	stat, err := fs.Stat(fsys, root)
 	if err != nil {
 		return nil, err
 	}
@@ -475,29 +766,35 @@ func ReadNoteResources(path string) ([]*NoteResource, error) {
 		return nil, errors.New("Quiver Note Resources should be held in a directory")
 	}
 
-	files, err := ioutil.ReadDir(path)
+	files, err := fs.ReadDir(fsys, root)
 	if err != nil {
 		return nil, err
 	}
 
 	res := make([]*NoteResource, len(files))
 	for i, file := range files {
-		name := file.Name()
-		fp := filepath.Join(path, name)
-
-		// Read the file completely in memory
-		f, err := os.Open(fp)
+		info, err := file.Info()
 		if err != nil {
 			return nil, err
 		}
-		buf, err := ioutil.ReadAll(f)
-		f.Close()
-		if err != nil {
-			return nil, err
+		r := &NoteResource{
+			Name:    file.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			fsys:    fsys,
+			path:    path.Join(root, file.Name()),
+		}
+
+		// Read the file completely in memory
+		if !lazy {
+			r.Data, err = fs.ReadFile(fsys, r.path)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		// And add the note to the list
-		res[i] = &NoteResource{name, buf}
+		res[i] = r
 	}
 
 	return res, nil
@@ -505,8 +802,14 @@ func ReadNoteResources(path string) ([]*NoteResource, error) {
 
 // ReadNoteResource loads the note "meta.json" at the given path.
 func ReadNoteMetadata(path string) (*NoteMetadata, error) {
+	fsys, name := dirFS(path)
+	return ReadNoteMetadataFS(fsys, name)
+}
+
+// ReadNoteMetadataFS loads the note "meta.json" with the given name in fsys.
+func ReadNoteMetadataFS(fsys fs.FS, name string) (*NoteMetadata, error) {
 	// find and read metadata file
-	mf, err := os.Open(path)
+	mf, err := fsys.Open(name)
 	if err != nil {
 		return nil, err
 	}
@@ -514,13 +817,20 @@ func ReadNoteMetadata(path string) (*NoteMetadata, error) {
 
 	// Read metadata
 	buf := bufio.NewReader(mf)
-	return ParseNoteMetadata(buf)
+	m, err := ParseNoteMetadata(buf)
+	return m, withPath(err, elementPath(fsys, name))
 }
 
 // ReadNoteContent loads the note "content.json" at the given path.
 func ReadNoteContent(path string) (*NoteContent, error) {
+	fsys, name := dirFS(path)
+	return ReadNoteContentFS(fsys, name)
+}
+
+// ReadNoteContentFS loads the note "content.json" with the given name in fsys.
+func ReadNoteContentFS(fsys fs.FS, name string) (*NoteContent, error) {
 	// find and read content file
-	cf, err := os.Open(path)
+	cf, err := fsys.Open(name)
 	if err != nil {
 		return nil, err
 	}
@@ -528,61 +838,139 @@ func ReadNoteContent(path string) (*NoteContent, error) {
 
 	// Read Content
 	buf := bufio.NewReader(cf)
-	return ParseContent(buf)
+	c, err := ParseContent(buf)
+	return c, withPath(err, elementPath(fsys, name))
 }
 
 // ReadNotebookMetadata loads the notebook "meta.json" at the given path.
 func ReadNotebookMetadata(path string) (*NotebookMetadata, error) {
-	f, err := os.Open(path)
+	fsys, name := dirFS(path)
+	return ReadNotebookMetadataFS(fsys, name)
+}
+
+// ReadNotebookMetadataFS loads the notebook "meta.json" with the given name in fsys.
+func ReadNotebookMetadataFS(fsys fs.FS, name string) (*NotebookMetadata, error) {
+	f, err := fsys.Open(name)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
 	buf := bufio.NewReader(f)
-	return ParseNotebookMetadata(buf)
+	m, err := ParseNotebookMetadata(buf)
+	return m, withPath(err, elementPath(fsys, name))
+}
+
+// dirFS splits an OS path into a file system rooted at its parent directory, and the name of the
+// element inside of it.
+func dirFS(p string) (fs.FS, string) {
+	p = filepath.Clean(p)
+	dir := filepath.Dir(p)
+	return osDirFS{os.DirFS(dir), dir}, filepath.Base(p)
+}
+
+// osDirFS is the file system used when loading elements from an OS path.
+// Unlike other file systems, it allows elements to be modified in place.
+type osDirFS struct {
+	fs.FS
+	dir string
+}
+
+// ReadDir, Stat and ReadFile keep the fast paths of the underlying os.DirFS file system.
+// All the errors hold OS paths, like the ones of the os package.
+func (o osDirFS) Open(name string) (fs.File, error) {
+	f, err := o.FS.Open(name)
+	return f, o.osError(err)
+}
+
+func (o osDirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	l, err := fs.ReadDir(o.FS, name)
+	return l, o.osError(err)
+}
+
+func (o osDirFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := fs.Stat(o.FS, name)
+	return info, o.osError(err)
+}
+
+func (o osDirFS) ReadFile(name string) ([]byte, error) {
+	data, err := fs.ReadFile(o.FS, name)
+	return data, o.osError(err)
+}
+
+// osError rewrites the paths of the errors of os.DirFS, which are relative to dir, into OS paths.
+func (o osDirFS) osError(err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) && !filepath.IsAbs(pe.Path) {
+		pe.Path = filepath.Join(o.dir, filepath.FromSlash(pe.Path))
+	}
+	return err
+}
+
+// ErrReadOnly is returned when trying to modify an element that was not loaded from an OS path.
+var ErrReadOnly = errors.New("the element was not loaded from an OS path, and cannot be modified")
+
+// osPath returns the OS path of the element with the given name in fsys.
+func osPath(fsys fs.FS, name string) (string, error) {
+	ofs, ok := fsys.(osDirFS)
+	if !ok {
+		return "", ErrReadOnly
+	}
+	return filepath.Join(ofs.dir, filepath.FromSlash(name)), nil
+}
+
+// elementPath returns the OS path of the element when available, or its path in fsys.
+func elementPath(fsys fs.FS, name string) string {
+	if p, err := osPath(fsys, name); err == nil {
+		return p
+	}
+	return name
 }
 
 // ParseLibraryMetadata loads the JSON from the given stream into a LibraryMetadata.
+// Parsing errors are returned as *ParseError.
 func ParseLibraryMetadata(r io.Reader) (*LibraryMetadata, error) {
 	d := json.NewDecoder(r)
 	m := new(LibraryMetadata)
 	err := d.Decode(m)
 	if err != nil {
-		return nil, err
+		return nil, newParseError(LibraryMetadataFile, err)
 	}
 	return m, nil
 }
 
 // ParseNotebookMetadata loads the JSON from the given stream into a NotebookMetadata.
+// Parsing errors are returned as *ParseError.
 func ParseNotebookMetadata(r io.Reader) (*NotebookMetadata, error) {
 	d := json.NewDecoder(r)
 	m := new(NotebookMetadata)
 	err := d.Decode(m)
 	if err != nil {
-		return nil, err
+		return nil, newParseError(NotebookMetadataFile, err)
 	}
 	return m, nil
 }
 
 // ParseNoteMetadata loads the JSON from the given stream into a NoteMetadata.
+// Parsing errors are returned as *ParseError.
 func ParseNoteMetadata(r io.Reader) (*NoteMetadata, error) {
 	d := json.NewDecoder(r)
 	m := new(NoteMetadata)
 	err := d.Decode(m)
 	if err != nil {
-		return nil, err
+		return nil, newParseError(NoteMetadataFile, err)
 	}
 	return m, nil
 }
 
-// ParseNoteMetadata loads the JSON from the given stream into a NoteContent.
+// ParseContent loads the JSON from the given stream into a NoteContent.
+// Parsing errors are returned as *ParseError.
 func ParseContent(r io.Reader) (*NoteContent, error) {
 	d := json.NewDecoder(r)
 	n := new(NoteContent)
 	err := d.Decode(n)
 	if err != nil {
-		return nil, err
+		return nil, newParseError(NoteContentFile, err)
 	}
-	return n, err
+	return n, nil
 }
@@ -1,8 +1,11 @@
 package quiver_test
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 
 	"github.com/ushu/quiver"
 )
@@ -23,6 +26,59 @@ func TestLoadLibrary(t *testing.T) {
 	}
 }
 
+func TestLoadLibraryFS(t *testing.T) {
+	t.Parallel()
+
+	// Ensure we can load the library from any fs.FS
+	lib, err := quiver.ReadLibraryFS(os.DirFS("testdata"), "Quiver.qvlibrary", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// It should have one notebook with 3 notes
+	if len(lib.Notebooks) != 1 {
+		t.Fatalf("len(lib.Notebooks) = %v; want %v", len(lib.Notebooks), 1)
+	}
+	if len(lib.Notebooks[0].Notes) != 3 {
+		t.Errorf("len(lib.Notebooks[0].Notes) = %v; want %v", len(lib.Notebooks[0].Notes), 3)
+	}
+}
+
+func TestLoadLibraryMapFS(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"Mem.qvlibrary/meta.json":               {Data: []byte(`{"children": [{"uuid": "NB"}]}`)},
+		"Mem.qvlibrary/NB.qvnotebook/meta.json": {Data: []byte(`{"name": "Memory", "uuid": "NB"}`)},
+		"Mem.qvlibrary/NB.qvnotebook/N.qvnote/meta.json": {Data: []byte(
+			`{"created_at": 1, "tags": [], "title": "In memory", "updated_at": 2, "uuid": "N"}`,
+		)},
+		"Mem.qvlibrary/NB.qvnotebook/N.qvnote/content.json": {Data: []byte(
+			`{"title": "In memory", "cells": [{"type": "text", "data": "Hello"}]}`,
+		)},
+		"Mem.qvlibrary/NB.qvnotebook/N.qvnote/resources/image.png": {Data: []byte("PNG")},
+	}
+
+	lib, err := quiver.ReadLibraryFS(fsys, "Mem.qvlibrary", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lib.Children) != 1 || lib.Children[0].UUID != "NB" {
+		t.Errorf("lib.Children = %v; want a single %q notebook", lib.Children, "NB")
+	}
+	if len(lib.Notebooks) != 1 || len(lib.Notebooks[0].Notes) != 1 {
+		t.Fatalf("unexpected library layout: %v notebooks", len(lib.Notebooks))
+	}
+	note := lib.Notebooks[0].Notes[0]
+	const title = "In memory"
+	if note.Title != title {
+		t.Errorf("note.Title = %q, want %q", note.Title, title)
+	}
+	if len(note.Resources) != 1 || string(note.Resources[0].Data) != "PNG" {
+		t.Errorf("note.Resources = %v; want a single %q resource", note.Resources, "image.png")
+	}
+}
+
 func TestLoadNotebook(t *testing.T) {
 	t.Parallel()
 	nbPath := fixturePath("Quiver.qvlibrary/Quiver Test.qvnotebook")
@@ -119,6 +175,43 @@ func TestLoadNoteSeveralResources(t *testing.T) {
 	}
 }
 
+func TestLoadNoteLazyResources(t *testing.T) {
+	t.Parallel()
+	notePath := fixturePath("Quiver.qvlibrary/Quiver Test.qvnotebook/B59AC519-2A2C-4EC8-B701-E69F54F40A85.qvnote")
+
+	// Ensure we can load the note
+	note, err := quiver.ReadNoteWithOptions(notePath, &quiver.ReadOptions{Resources: quiver.LazyResources})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(note.Resources) != 2 {
+		t.Fatalf("len(Note.Resources) = %v; want %v", len(note.Resources), 2)
+	}
+	for _, r := range note.Resources {
+		// data should not be loaded until asked
+		if r.Data != nil {
+			t.Errorf("%v: r.Data should be nil", r.Name)
+		}
+
+		f, err := r.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(data)) != r.Size || r.Size == 0 {
+			t.Errorf("%v: len(data) = %v; want %v", r.Name, len(data), r.Size)
+		}
+		if r.ModTime.IsZero() {
+			t.Errorf("%v: r.ModTime should be set", r.Name)
+		}
+	}
+}
+
 func TestLoadNoteSeveralCells(t *testing.T) {
 	t.Parallel()
 	notePath := fixturePath("Quiver.qvlibrary/Quiver Test.qvnotebook/D2A1CC36-CC97-4701-A895-EFC98EF47026.qvnote")
@@ -155,6 +248,73 @@ func fixturePath(p string) string {
 	return filepath.Join("testdata", p)
 }
 
+// copyFixture copies the fixture into a temporary directory, and returns its new path.
+func copyFixture(t *testing.T, p string) string {
+	t.Helper()
+	src := fixturePath(p)
+	dst := filepath.Join(t.TempDir(), filepath.Base(p))
+
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dst
+}
+
+// buildNote builds a note with text cells
+func buildNote(uuid, title string, tags []string, cells ...string) *quiver.Note {
+	n := &quiver.Note{
+		NoteMetadata: &quiver.NoteMetadata{UUID: uuid, Title: title, Tags: tags},
+		NoteContent:  &quiver.NoteContent{Cells: []*quiver.Cell{}},
+	}
+	for _, c := range cells {
+		n.Cells = append(n.Cells, &quiver.Cell{Type: quiver.TextCell, Data: c})
+	}
+	return n
+}
+
+// buildLibrary builds a library with the given hierarchy, and notebooks holding the given notes
+func buildLibrary(hierarchy []quiver.NotebookHierarchyInfo, notebooks map[string][]*quiver.Note) *quiver.Library {
+	lib := &quiver.Library{LibraryMetadata: &quiver.LibraryMetadata{Children: hierarchy}}
+	for _, uuid := range []string{"NB1", "NB2", "NB3"} {
+		if notes, ok := notebooks[uuid]; ok {
+			lib.Notebooks = append(lib.Notebooks, &quiver.Notebook{
+				NotebookMetadata: &quiver.NotebookMetadata{UUID: uuid, Name: uuid},
+				Notes:            notes,
+			})
+		}
+	}
+	lib.Reindex()
+	return lib
+}
+
+// cellsData returns the data of the cells of the note
+func cellsData(n *quiver.Note) []string {
+	var data []string
+	for _, c := range n.Cells {
+		data = append(data, c.Data)
+	}
+	return data
+}
+
 func stringSliceEqual(l []string, r []string) bool {
 	if len(l) != len(r) {
 		return false
@@ -0,0 +1,126 @@
+package quiver
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+)
+
+// resourceURLRegexp matches the references to resources, as found in the cells.
+var resourceURLRegexp = regexp.MustCompile(`quiver-(?:image|file)-url/([^"'\s<>(){}]+)`)
+
+// ResourceReport lists the inconsistencies between the resources referenced by the cells of a note, and the
+// resources actually stored in its resources/ directory.
+type ResourceReport struct {
+	// The analyzed note.
+	Note *Note
+	// The names of the resources referenced by the cells, but not found on disk.
+	Missing []string
+	// The resources found on disk, but not referenced by any cell.
+	Orphaned []*NoteResource
+}
+
+// OK returns true when no inconsistency was found.
+func (r *ResourceReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Orphaned) == 0
+}
+
+// PruneOrphans deletes the orphaned resources from disk, and removes them from the note.
+// The note should have been loaded from an OS path, otherwise ErrReadOnly is returned.
+func (r *ResourceReport) PruneOrphans() error {
+	for len(r.Orphaned) > 0 {
+		res := r.Orphaned[0]
+		err := res.Remove()
+		if err != nil {
+			return err
+		}
+		r.Orphaned = r.Orphaned[1:]
+
+		// and forget about the resource
+		for i, nr := range r.Note.Resources {
+			if nr == res {
+				r.Note.Resources = append(r.Note.Resources[:i], r.Note.Resources[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Remove deletes the resource file from disk.
+// The resource should have been loaded from an OS path, otherwise ErrReadOnly is returned.
+func (n *NoteResource) Remove() error {
+	p, err := osPath(n.fsys, n.path)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+// ReferencedResources returns the names of all the resources referenced by the cells of the note, in order and
+// without duplicates.
+// Both "quiver-image-url/<name>" and "quiver-file-url/<name>" references are recognized, in cells of any type, and
+// the names are unescaped ("My%20File.pdf" references "My File.pdf").
+func (n *Note) ReferencedResources() []string {
+	if n.NoteContent == nil {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, c := range n.Cells {
+		for _, m := range resourceURLRegexp.FindAllStringSubmatch(c.Data, -1) {
+			name, err := url.PathUnescape(m[1])
+			if err != nil {
+				// not a valid escape sequence: keep the name as written
+				name = m[1]
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// CheckResources compares the resources referenced by the cells of the note with its Resources.
+//
+// The note should have been loaded with resources (see ReadOptions.Resources), otherwise all the references are
+// reported as missing.
+func (n *Note) CheckResources() *ResourceReport {
+	report := &ResourceReport{Note: n}
+
+	stored := make(map[string]bool, len(n.Resources))
+	for _, r := range n.Resources {
+		stored[r.Name] = true
+	}
+	referenced := make(map[string]bool)
+	for _, name := range n.ReferencedResources() {
+		referenced[name] = true
+		if !stored[name] {
+			report.Missing = append(report.Missing, name)
+		}
+	}
+	for _, r := range n.Resources {
+		if !referenced[r.Name] {
+			report.Orphaned = append(report.Orphaned, r)
+		}
+	}
+
+	return report
+}
+
+// CheckResources checks the resources of all the notes of the library, and returns the reports of the notes with
+// missing or orphaned resources, in library order.
+func (m *Library) CheckResources() []*ResourceReport {
+	var reports []*ResourceReport
+	for _, nb := range m.Notebooks {
+		for _, n := range nb.Notes {
+			if r := n.CheckResources(); !r.OK() {
+				reports = append(reports, r)
+			}
+		}
+	}
+	return reports
+}
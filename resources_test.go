@@ -0,0 +1,88 @@
+package quiver_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ushu/quiver"
+)
+
+const resourcesNote = "Quiver.qvlibrary/Quiver Test.qvnotebook/B59AC519-2A2C-4EC8-B701-E69F54F40A85.qvnote"
+
+func TestReferencedResources(t *testing.T) {
+	t.Parallel()
+	note, err := quiver.ReadNote(fixturePath(resourcesNote), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := note.ReferencedResources()
+	want := []string{
+		"1C3392AA-54E7-4EA3-A129-1C20F208B029.jpg",
+		"F6E1CA4A-FA0B-4E45-9861-3E3FEB0DAF99.png",
+	}
+	if !stringSliceEqual(names, want) {
+		t.Errorf("note.ReferencedResources() = %q; want %q", names, want)
+	}
+
+	// Without resources, all the references are missing
+	report := note.CheckResources()
+	if !stringSliceEqual(report.Missing, want) || len(report.Orphaned) != 0 {
+		t.Errorf("report = %v, %v; want all resources missing", report.Missing, report.Orphaned)
+	}
+}
+
+func TestReferencedResourcesAllCells(t *testing.T) {
+	t.Parallel()
+	note := &quiver.Note{NoteContent: &quiver.NoteContent{Cells: []*quiver.Cell{
+		{Type: quiver.MarkdownCell, Data: "[doc](quiver-file-url/My%20File.pdf)"},
+		{Type: quiver.CodeCell, Data: "// see quiver-image-url/diagram.png"},
+		{Type: quiver.LatexCell, Data: "\\includegraphics{quiver-image-url/formula.png}"},
+		{Type: quiver.TextCell, Data: `<a href="quiver-file-url/My%20File.pdf">again</a>`},
+	}}}
+
+	names := note.ReferencedResources()
+	want := []string{"My File.pdf", "diagram.png", "formula.png"}
+	if !stringSliceEqual(names, want) {
+		t.Errorf("note.ReferencedResources() = %q; want %q", names, want)
+	}
+}
+
+func TestCheckAndPruneResources(t *testing.T) {
+	t.Parallel()
+	libPath := copyFixture(t, "Quiver.qvlibrary")
+
+	// Add an orphaned resource
+	orphan := filepath.Join(libPath, "Quiver Test.qvnotebook", filepath.Base(resourcesNote), "resources", "orphan.png")
+	err := ioutil.WriteFile(orphan, []byte("PNG"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lib, err := quiver.ReadLibraryWithOptions(libPath, &quiver.ReadOptions{Resources: quiver.LazyResources})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := lib.CheckResources()
+	if len(reports) != 1 {
+		t.Fatalf("len(reports) = %v; want %v", len(reports), 1)
+	}
+	r := reports[0]
+	if len(r.Missing) != 0 || len(r.Orphaned) != 1 || r.Orphaned[0].Name != "orphan.png" {
+		t.Fatalf("report = %v, %v; want a single orphan", r.Missing, r.Orphaned)
+	}
+
+	// Prune it
+	err = r.PruneOrphans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("the orphaned resource should be deleted, got %v", err)
+	}
+	if len(r.Note.Resources) != 2 || !r.Note.CheckResources().OK() {
+		t.Errorf("len(r.Note.Resources) = %v; want %v", len(r.Note.Resources), 2)
+	}
+}
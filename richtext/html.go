@@ -0,0 +1,649 @@
+package richtext
+
+import (
+	"html"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FromText converts plain text to the HTML of a text cell: each line is held in a <div>, and the empty lines are
+// <div><br></div>, like the Quiver app does.
+func FromText(s string) string {
+	var b strings.Builder
+	for _, l := range splitLines(s) {
+		writeLine(&b, escapeText(l))
+	}
+	return b.String()
+}
+
+// FromMarkdown converts GitHub Flavored Markdown to the HTML of a text cell.
+//
+// The paragraphs are held in <div> elements, separated with empty <div><br></div> lines, like the Quiver app does.
+// Images with a relative source ("_resources/image.png", as written by the Markdown export) are turned into
+// references to the note resources ("quiver-image-url/image.png"): such resources should be added to the note.
+func FromMarkdown(md string) string {
+	var b strings.Builder
+	for i, blk := range parseBlocks(splitLines(md)) {
+		if i > 0 {
+			b.WriteString("<div><br></div>")
+		}
+		blk.render(&b, true)
+	}
+	return b.String()
+}
+
+// writeLine writes a Quiver line of text.
+func writeLine(b *strings.Builder, s string) {
+	if s == "" {
+		s = "<br>"
+	}
+	b.WriteString("<div>" + s + "</div>")
+}
+
+// block is a Markdown block.
+type block struct {
+	// One of: p, h1…h6, hr, pre, blockquote, ul, ol, li, table.
+	kind string
+	// The lines of the block (for paragraphs, headings, code blocks and table rows).
+	lines []string
+	// The child blocks (for lists, items and quotes).
+	children []*block
+	// The first number of ordered lists.
+	start int
+	// The language of code blocks.
+	info string
+}
+
+var (
+	headingRegexp    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	ruleRegexp       = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceRegexp      = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+	quoteRegexp      = regexp.MustCompile(`^ {0,3}> ?`)
+	itemRegexp       = regexp.MustCompile(`^( {0,3})([-+*]|(\d{1,9})[.)])(?:[ \t]+|$)`)
+	setextRegexp     = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	tableDelimRegexp = regexp.MustCompile(`^ *\|?(?: *:?-+:? *\|)*(?: *:?-+:? *)\|? *$`)
+)
+
+// parseBlocks splits the lines into blocks.
+func parseBlocks(lines []string) []*block {
+	var blocks []*block
+	var para *block
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+
+		// paragraph continuation
+		if para != nil {
+			if m := setextRegexp.FindStringSubmatch(l); m != nil {
+				para.kind = "h2"
+				if m[1][0] == '=' {
+					para.kind = "h1"
+				}
+				para = nil
+				continue
+			}
+			if strings.TrimSpace(l) != "" && !startsBlock(l) {
+				para.lines = append(para.lines, l)
+				continue
+			}
+			para = nil
+		}
+
+		switch {
+		case strings.TrimSpace(l) == "":
+			// blocks separator
+		case ruleRegexp.MatchString(l):
+			blocks = append(blocks, &block{kind: "hr"})
+		case headingRegexp.MatchString(l):
+			m := headingRegexp.FindStringSubmatch(l)
+			blocks = append(blocks, &block{kind: "h" + strconv.Itoa(len(m[1])), lines: []string{m[2]}})
+		case fenceRegexp.MatchString(l):
+			m := fenceRegexp.FindStringSubmatch(l)
+			indent, marker := len(m[1]), m[2]
+			code := &block{kind: "pre", info: m[3]}
+			for i++; i < len(lines); i++ {
+				if t := strings.TrimSpace(lines[i]); strings.HasPrefix(t, marker) && strings.Trim(t, marker[:1]) == "" {
+					break
+				}
+				code.lines = append(code.lines, trimIndent(lines[i], indent))
+			}
+			blocks = append(blocks, code)
+		case strings.HasPrefix(l, "    ") || strings.HasPrefix(l, "\t"):
+			code := &block{kind: "pre"}
+			for ; i < len(lines) && (strings.TrimSpace(lines[i]) == "" || indentOf(lines[i]) >= 4); i++ {
+				code.lines = append(code.lines, trimIndent(lines[i], 4))
+			}
+			i--
+			// trailing empty lines are not part of the code
+			for len(code.lines) > 0 && strings.TrimSpace(code.lines[len(code.lines)-1]) == "" {
+				code.lines = code.lines[:len(code.lines)-1]
+			}
+			blocks = append(blocks, code)
+		case quoteRegexp.MatchString(l):
+			var quoted []string
+			for ; i < len(lines) && quoteRegexp.MatchString(lines[i]); i++ {
+				quoted = append(quoted, quoteRegexp.ReplaceAllString(lines[i], ""))
+			}
+			i--
+			blocks = append(blocks, &block{kind: "blockquote", children: parseBlocks(quoted)})
+		case itemRegexp.MatchString(l):
+			var list *block
+			list, i = parseList(lines, i)
+			blocks = append(blocks, list)
+			i--
+		case strings.Contains(l, "|") && i+1 < len(lines) && tableDelimRegexp.MatchString(lines[i+1]) &&
+			strings.Contains(lines[i+1], "-"):
+			table := &block{kind: "table", lines: []string{l}}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				table.lines = append(table.lines, lines[i])
+			}
+			i--
+			blocks = append(blocks, table)
+		default:
+			para = &block{kind: "p", lines: []string{l}}
+			blocks = append(blocks, para)
+		}
+	}
+	return blocks
+}
+
+// parseList parses the list starting at lines[i], and returns the index of the first line after it.
+func parseList(lines []string, i int) (*block, int) {
+	m := itemRegexp.FindStringSubmatch(lines[i])
+	list := &block{kind: "ul"}
+	if m[3] != "" {
+		list.kind = "ol"
+		list.start, _ = strconv.Atoi(m[3])
+	}
+	marker := m[2][len(m[2])-1:]
+
+	for i < len(lines) {
+		m := itemRegexp.FindStringSubmatch(lines[i])
+		if m == nil || m[2][len(m[2])-1:] != marker {
+			break
+		}
+		// the contents of the item are indented after the marker
+		width := len(m[0])
+		if strings.TrimSpace(lines[i][width:]) == "" {
+			width = len(m[1]) + len(m[2]) + 1
+		}
+		item := []string{lines[i][len(m[0]):]}
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" {
+				// an empty line ends the list, unless the next one is indented
+				if i+1 < len(lines) && indentOf(lines[i+1]) >= width {
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			if indentOf(l) >= width {
+				item = append(item, trimIndent(l, width))
+			} else if itemRegexp.MatchString(l) || startsBlock(l) {
+				break
+			} else {
+				// lazy continuation of the paragraph
+				item = append(item, strings.TrimSpace(l))
+			}
+		}
+		list.children = append(list.children, &block{kind: "li", children: parseBlocks(item)})
+
+		// skip the empty lines between the items
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" && i+1 < len(lines) && itemRegexp.MatchString(lines[i+1]) {
+			i++
+		}
+	}
+	return list, i
+}
+
+// startsBlock tells if the line interrupts a paragraph.
+func startsBlock(l string) bool {
+	return ruleRegexp.MatchString(l) || headingRegexp.MatchString(l) || fenceRegexp.MatchString(l) ||
+		quoteRegexp.MatchString(l) || itemRegexp.MatchString(l)
+}
+
+// render writes the HTML of the block. Top-level paragraphs are held in <div> elements.
+func (blk *block) render(b *strings.Builder, top bool) {
+	switch blk.kind {
+	case "p":
+		if top {
+			b.WriteString("<div>" + renderSpans(blk.lines) + "</div>")
+		} else {
+			b.WriteString(renderSpans(blk.lines))
+		}
+	case "hr":
+		b.WriteString("<hr>")
+	case "pre":
+		b.WriteString("<pre>")
+		if blk.info != "" {
+			b.WriteString(`<code class="language-` + html.EscapeString(blk.info) + `">`)
+		}
+		b.WriteString(html.EscapeString(strings.Join(blk.lines, "\n")))
+		if blk.info != "" {
+			b.WriteString("</code>")
+		}
+		b.WriteString("</pre>")
+	case "blockquote":
+		b.WriteString("<blockquote>")
+		for i, c := range blk.children {
+			if i > 0 {
+				b.WriteString("<div><br></div>")
+			}
+			c.render(b, true)
+		}
+		b.WriteString("</blockquote>")
+	case "ul", "ol":
+		b.WriteString("<" + blk.kind)
+		if blk.kind == "ol" && blk.start != 1 {
+			b.WriteString(` start="` + strconv.Itoa(blk.start) + `"`)
+		}
+		b.WriteString(">")
+		for _, c := range blk.children {
+			c.render(b, false)
+		}
+		b.WriteString("</" + blk.kind + ">")
+	case "li":
+		b.WriteString("<li>")
+		paras := 0
+		for _, c := range blk.children {
+			if c.kind == "p" {
+				paras++
+			}
+		}
+		for _, c := range blk.children {
+			// a single paragraph is written inline, like the Quiver app does
+			c.render(b, paras > 1)
+		}
+		b.WriteString("</li>")
+	case "table":
+		b.WriteString("<table><tbody>")
+		for i, row := range blk.lines {
+			tag := "td"
+			if i == 0 {
+				tag = "th"
+			}
+			b.WriteString("<tr>")
+			for _, cell := range splitRow(row) {
+				b.WriteString("<" + tag + ">" + renderSpans([]string{cell}) + "</" + tag + ">")
+			}
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody></table>")
+	default:
+		// headings
+		b.WriteString("<" + blk.kind + ">" + renderSpans(blk.lines) + "</" + blk.kind + ">")
+	}
+}
+
+// splitRow splits a table row on unescaped pipes.
+func splitRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+	var cells []string
+	var cur strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cur.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(row[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
+
+// renderSpans renders the lines of a paragraph: lines ending with two spaces (or a backslash) are hard breaks.
+func renderSpans(lines []string) string {
+	var b strings.Builder
+	for i, l := range lines {
+		l = strings.TrimLeft(l, " \t")
+		brk := false
+		if i < len(lines)-1 {
+			if strings.HasSuffix(l, "  ") {
+				brk = true
+			} else if strings.HasSuffix(l, `\`) && !strings.HasSuffix(l, `\\`) {
+				brk = true
+				l = l[:len(l)-1]
+			}
+		}
+		b.WriteString(renderInlineMarkdown(strings.TrimRight(l, " \t")))
+		if brk {
+			b.WriteString("<br>")
+		} else if i < len(lines)-1 {
+			b.WriteString(" ")
+		}
+	}
+	return b.String()
+}
+
+// The HTML tags allowed in the Markdown, and their Quiver equivalents
+var inlineTags = map[string]string{
+	"<br>": "<br>", "<br/>": "<br>", "<br />": "<br>",
+	"<ins>": "<u>", "</ins>": "</u>", "<u>": "<u>", "</u>": "</u>",
+	"<sub>": "<sub>", "</sub>": "</sub>", "<sup>": "<sup>", "</sup>": "</sup>",
+}
+
+// The HTML tags of the emphasis delimiters
+var emphasisTags = map[string]string{
+	"*": "i", "_": "i", "**": "b", "__": "b", "~~": "strike",
+}
+
+// delimiter is an opened emphasis, waiting to be closed.
+type delimiter struct {
+	marker string
+	// the index of its placeholder in the output
+	index int
+}
+
+// renderInlineMarkdown renders the inline Markdown (emphasis, code spans, links, images…) of a line.
+func renderInlineMarkdown(s string) string {
+	var out []string
+	var stack []delimiter
+	var text strings.Builder
+	flushText := func() {
+		if text.Len() > 0 {
+			out = append(out, html.EscapeString(text.String()))
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			text.WriteByte(s[i+1])
+			i += 2
+
+		case c == '`':
+			n := runLength(s, i, '`')
+			ticks := s[i : i+n]
+			end := strings.Index(s[i+n:], ticks)
+			for end >= 0 && i+n+end+n < len(s) && s[i+n+end+n] == '`' {
+				// longer run: not the closing one
+				next := strings.Index(s[i+n+end+n:], ticks)
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += n + next
+			}
+			if end < 0 {
+				text.WriteString(ticks)
+				i += n
+				break
+			}
+			code := s[i+n : i+n+end]
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			flushText()
+			out = append(out, "<code>"+html.EscapeString(code)+"</code>")
+			i += n + end + n
+
+		case c == '!' && i+1 < len(s) && s[i+1] == '[', c == '[':
+			image := c == '!'
+			start := i
+			if image {
+				start++
+			}
+			label, dest, end, ok := parseLink(s, start)
+			if !ok {
+				text.WriteByte(c)
+				i++
+				break
+			}
+			flushText()
+			if image {
+				out = append(out, `<img src="`+html.EscapeString(imageURL(dest))+`" alt="`+html.EscapeString(plainText(label))+`">`)
+			} else {
+				out = append(out, `<a href="`+html.EscapeString(dest)+`">`+renderInlineMarkdown(label)+`</a>`)
+			}
+			i = end
+
+		case c == '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				text.WriteByte(c)
+				i++
+				break
+			}
+			tag := s[i : i+end+1]
+			if inner := tag[1 : len(tag)-1]; isAutolink(inner) {
+				flushText()
+				out = append(out, `<a href="`+html.EscapeString(inner)+`">`+html.EscapeString(inner)+`</a>`)
+			} else if t, ok := inlineTags[strings.ToLower(tag)]; ok {
+				flushText()
+				out = append(out, t)
+			} else {
+				text.WriteByte(c)
+				i++
+				break
+			}
+			i += end + 1
+
+		case c == '*' || c == '_' || c == '~':
+			n := runLength(s, i, c)
+			if c == '~' && n != 2 {
+				text.WriteString(s[i : i+n])
+				i += n
+				break
+			}
+			before, after := byte(' '), byte(' ')
+			if i > 0 {
+				before = s[i-1]
+			}
+			if i+n < len(s) {
+				after = s[i+n]
+			}
+			canOpen := !isSpaceByte(after) && !(c == '_' && isWordByte(before))
+			canClose := !isSpaceByte(before) && !(c == '_' && isWordByte(after))
+
+			flushText()
+			remaining := n
+			// close the opened emphasis first, innermost first
+			for canClose && remaining > 0 {
+				j := len(stack) - 1
+				for j >= 0 && (stack[j].marker[0] != c || len(stack[j].marker) > remaining) {
+					j--
+				}
+				if j < 0 {
+					break
+				}
+				d := stack[j]
+				tag := emphasisTags[d.marker]
+				out[d.index] = "<" + tag + ">"
+				out = append(out, "</"+tag+">")
+				remaining -= len(d.marker)
+				stack = stack[:j]
+			}
+			// then open the new ones
+			for canOpen && remaining > 0 {
+				m := remaining
+				if m > 2 {
+					m = 2
+				}
+				if c == '~' {
+					m = 2
+				}
+				stack = append(stack, delimiter{strings.Repeat(string(c), m), len(out)})
+				out = append(out, strings.Repeat(string(c), m))
+				remaining -= m
+			}
+			if remaining > 0 {
+				out = append(out, strings.Repeat(string(c), remaining))
+			}
+			i += n
+
+		default:
+			text.WriteByte(c)
+			i++
+		}
+	}
+	flushText()
+
+	// the unclosed delimiters are kept as text (they were already written as such)
+	return strings.Join(out, "")
+}
+
+// parseLink parses a link "[label](destination)" at s[i], and returns the index of the end of the link.
+func parseLink(s string, i int) (label, dest string, end int, ok bool) {
+	// the label, with nested brackets
+	depth := 0
+	j := i
+	for ; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+			continue
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	if j >= len(s)-1 || s[j+1] != '(' {
+		return "", "", 0, false
+	}
+	label = s[i+1 : j]
+
+	// the destination, with an optional title
+	k := j + 2
+	if k < len(s) && s[k] == '<' {
+		e := strings.IndexByte(s[k:], '>')
+		if e < 0 {
+			return "", "", 0, false
+		}
+		dest = s[k+1 : k+e]
+		k += e + 1
+	} else {
+		start := k
+		parens := 0
+		for ; k < len(s) && !isSpaceByte(s[k]); k++ {
+			if s[k] == '(' {
+				parens++
+			} else if s[k] == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+		}
+		dest = s[start:k]
+	}
+	e := strings.IndexByte(s[k:], ')')
+	if e < 0 {
+		return "", "", 0, false
+	}
+	return label, unescapeMarkdown(dest), k + e + 1, true
+}
+
+// imageURL turns relative image sources into references to the resources of the note.
+func imageURL(src string) string {
+	if src == "" || strings.Contains(src, ":") || strings.HasPrefix(src, "/") ||
+		strings.HasPrefix(src, "quiver-image-url/") {
+		return src
+	}
+	return "quiver-image-url/" + path.Base(src)
+}
+
+var autolinkRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*$`)
+
+func isAutolink(s string) bool {
+	return autolinkRegexp.MatchString(s)
+}
+
+// plainText removes the Markdown formatting of s, for image descriptions.
+func plainText(s string) string {
+	return unescapeMarkdown(strings.NewReplacer("**", "", "__", "", "*", "", "`", "").Replace(s))
+}
+
+var markdownEscapeRegexp = regexp.MustCompile("\\\\([!-/:-@\\[-`{-~])")
+
+func unescapeMarkdown(s string) string {
+	return markdownEscapeRegexp.ReplaceAllString(s, "$1")
+}
+
+// escapeText escapes the text for HTML, keeping the repeated spaces with &nbsp;.
+func escapeText(s string) string {
+	s = html.EscapeString(strings.Replace(s, "\t", "    ", -1))
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' && (i == 0 || s[i-1] == ' ' || i == len(s)-1) {
+			b.WriteString("&nbsp;")
+		} else {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// splitLines splits the text into lines, handling all the line endings.
+func splitLines(s string) []string {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	s = strings.Replace(s, "\r", "\n", -1)
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// indentOf returns the number of leading spaces of the line (a tab counts as 4 spaces).
+func indentOf(l string) int {
+	n := 0
+	for _, c := range l {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 4 - n%4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// trimIndent removes up to n columns of indentation.
+func trimIndent(l string, n int) string {
+	col := 0
+	for i, c := range l {
+		if col >= n || (c != ' ' && c != '\t') {
+			return l[i:]
+		}
+		if c == '\t' {
+			col += 4 - col%4
+		} else {
+			col++
+		}
+	}
+	return ""
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func isPunct(b byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", b) >= 0
+}
+
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n'
+}
@@ -0,0 +1,77 @@
+package richtext_test
+
+import (
+	"testing"
+
+	"github.com/ushu/quiver/richtext"
+)
+
+func TestFromText(t *testing.T) {
+	t.Parallel()
+
+	got := richtext.FromText("Hello <world> & co\n\n  indented\r\nlast")
+	want := "<div>Hello &lt;world&gt; &amp; co</div><div><br></div><div>&nbsp;&nbsp;indented</div><div>last</div>"
+	if got != want {
+		t.Errorf("FromText() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFromMarkdown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		md   string
+		want string
+	}{
+		{"Hello", "<div>Hello</div>"},
+		{"One\ntwo  \nthree\n\nFour", "<div>One two<br>three</div><div><br></div><div>Four</div>"},
+		{"# Title\n\nSub\n---", "<h1>Title</h1><div><br></div><h2>Sub</h2>"},
+		{
+			"Some **bold**, *italics*, _more_, ***both***, ~~dashed~~ and <ins>underlined</ins> text.",
+			"<div>Some <b>bold</b>, <i>italics</i>, <i>more</i>, <b><i>both</i></b>, <strike>dashed</strike> and <u>underlined</u> text.</div>",
+		},
+		{"snake_case_name, 2 * 3 and **unclosed", "<div>snake_case_name, 2 * 3 and **unclosed</div>"},
+		{"Run `a < b` or ``a`b``", "<div>Run <code>a &lt; b</code> or <code>a`b</code></div>"},
+		{`\*not\* <b>html</b>`, "<div>*not* &lt;b&gt;html&lt;/b&gt;</div>"},
+		{
+			"[Apple](http://www.apple.com) and <https://golang.org>",
+			`<div><a href="http://www.apple.com">Apple</a> and <a href="https://golang.org">https://golang.org</a></div>`,
+		},
+		{"[**Other** note](quiver-note-url/ABC)", `<div><a href="quiver-note-url/ABC"><b>Other</b> note</a></div>`},
+		{
+			"![A cat](_resources/cat.png) ![](https://x.org/a.png)",
+			`<div><img src="quiver-image-url/cat.png" alt="A cat"> <img src="https://x.org/a.png" alt=""></div>`,
+		},
+		{"- One\n- Two\n  - Nested\n\n1. First", "<ul><li>One</li><li>Two<ul><li>Nested</li></ul></li></ul><div><br></div><ol><li>First</li></ol>"},
+		{"3. Three\n4. Four", `<ol start="3"><li>Three</li><li>Four</li></ol>`},
+		{"> Quoted\n> text", "<blockquote><div>Quoted text</div></blockquote>"},
+		{"```go\nif a < b {\n\treturn\n}\n```", `<pre><code class="language-go">if a &lt; b {` + "\n\treturn\n}</code></pre>"},
+		{"    indented\n    code", "<pre>indented\ncode</pre>"},
+		{"Before\n\n---\n\nAfter", "<div>Before</div><div><br></div><hr><div><br></div><div>After</div>"},
+		{
+			"| Name | Value |\n| --- | ---: |\n| a\\|b | **1** |",
+			"<table><tbody><tr><th>Name</th><th>Value</th></tr><tr><td>a|b</td><td><b>1</b></td></tr></tbody></table>",
+		},
+	}
+	for _, tt := range tests {
+		if got := richtext.FromMarkdown(tt.md); got != tt.want {
+			t.Errorf("FromMarkdown(%q) =\n%q\nwant\n%q", tt.md, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, md := range []string{
+		"Some **bold**, *italics*, <ins>underlined</ins> and ~~dashed~~ text.",
+		"# Title\n\nA paragraph  \nwith a line break.\n\n- One\n- Two\n  - Nested",
+		"[A link](quiver-note-url/ABC) and ![](quiver-image-url/A.png)",
+		"| Name | Value |\n| --- | --- |\n| a | `b` |",
+		"> Quoted\n\n```go\nfunc main() {}\n```",
+	} {
+		if got := richtext.ToMarkdown(richtext.FromMarkdown(md)); got != md {
+			t.Errorf("ToMarkdown(FromMarkdown(%q)) =\n%q", md, got)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+/*
+Package richtext converts the rich text of Quiver text cells (TextCell), which is stored as HTML, to and from
+GitHub Flavored Markdown.
+
+	md := richtext.ToMarkdown(`<div>Some <b>bold</b> text</div><div><br></div><div><img src="quiver-image-url/A.png"></div>`)
+	// Some **bold** text
+	//
+	// ![](quiver-image-url/A.png)
+
+Like in the Quiver app, each <div> holds a line: consecutive lines are joined with hard line breaks, and the empty
+<div><br></div> lines separate the paragraphs, so that FromText is the inverse of ToMarkdown.
+
+The links and images are kept as-is: references to the note resources (quiver-image-url/…) and to other notes
+(quiver-note-url/…) can be rewritten before or after the conversion.
+
+The other way around, FromMarkdown and FromText build the HTML of new text cells:
+
+	cell := &quiver.Cell{Type: quiver.TextCell, Data: richtext.FromMarkdown("Some **bold** text")}
+*/
+package richtext
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The elements rendered as Markdown blocks
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true, "dd": true, "details": true,
+	"dl": true, "dt": true, "div": true, "figcaption": true, "figure": true, "footer": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "html": true, "li": true,
+	"main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true, "summary": true, "table": true,
+	"ul": true,
+}
+
+// ToMarkdown converts the HTML of a text cell to GitHub Flavored Markdown.
+func ToMarkdown(s string) string {
+	return renderBlocks(parse(s), "\n\n")
+}
+
+// renderBlocks renders the children of n as a list of blocks, joined with sep.
+func renderBlocks(n *node, sep string) string {
+	var blocks []string
+	var inline strings.Builder
+	flush := func() {
+		blocks = append(blocks, paragraphs(inline.String())...)
+		inline.Reset()
+	}
+
+	for _, c := range n.children {
+		if !blockElements[c.tag] {
+			renderInline(&inline, c)
+			continue
+		}
+		if isLine(c) {
+			renderLine(&inline, c)
+			continue
+		}
+		flush()
+		if b := renderBlock(c); b != "" {
+			blocks = append(blocks, b)
+		}
+	}
+	flush()
+	return strings.Join(blocks, sep)
+}
+
+// isLine tells if n is a Quiver line: a <div> holding inline contents only.
+func isLine(n *node) bool {
+	if n.tag != "div" {
+		return false
+	}
+	var inline func(n *node) bool
+	inline = func(n *node) bool {
+		for _, c := range n.children {
+			if blockElements[c.tag] || !inline(c) {
+				return false
+			}
+		}
+		return true
+	}
+	return inline(n)
+}
+
+// renderLine renders a Quiver line on its own line of the inline Markdown: the empty lines (<div><br></div>) end
+// the paragraphs, and the other ones become hard line breaks.
+func renderLine(b *strings.Builder, n *node) {
+	if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+		b.WriteString("\n")
+	}
+	// (the trailing <br> of a line does not make another one)
+	b.WriteString(strings.TrimSuffix(inlineText(n), "\n") + "\n")
+}
+
+// renderBlock renders a block element.
+func renderBlock(n *node) string {
+	switch n.tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(n.tag[1:])
+		text := strings.Join(lines(inlineText(n)), " ")
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", level) + " " + text
+	case "hr":
+		return "---"
+	case "ul", "ol":
+		return renderList(n)
+	case "blockquote":
+		return prefixLines(renderBlocks(n, "\n\n"), "> ", ">")
+	case "pre":
+		return fence(strings.Trim(textContent(n), "\n"), "```", codeLanguage(n))
+	case "table":
+		return renderTable(n)
+	default:
+		return renderBlocks(n, "\n\n")
+	}
+}
+
+func renderList(n *node) string {
+	var items []string
+	i := 1
+	if start, err := strconv.Atoi(n.attr("start")); err == nil {
+		i = start
+	}
+	for _, c := range n.children {
+		if c.tag != "li" {
+			// stray contents: wrap them in their own item
+			if c.tag == "" && strings.TrimSpace(c.text) == "" {
+				continue
+			}
+			c = &node{tag: "li", children: []*node{c}}
+		}
+		marker := "- "
+		if n.tag == "ol" {
+			marker = strconv.Itoa(i) + ". "
+			i++
+		}
+		// the continuation lines are aligned with the contents of the first one
+		item := prefixLines(renderBlocks(c, "\n"), strings.Repeat(" ", len(marker)), "")
+		items = append(items, strings.TrimRight(marker+strings.TrimLeft(item, " "), " "))
+	}
+	return strings.Join(items, "\n")
+}
+
+func renderTable(n *node) string {
+	// collect the rows, from the table itself or from its sections
+	var rows [][]string
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _, c := range n.children {
+			switch c.tag {
+			case "thead", "tbody", "tfoot":
+				walk(c)
+			case "tr":
+				var row []string
+				for _, cell := range c.children {
+					if cell.tag == "td" || cell.tag == "th" {
+						text := strings.Join(lines(inlineText(cell)), " ")
+						row = append(row, strings.Replace(text, "|", `\|`, -1))
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(n)
+
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	if cols == 0 {
+		return ""
+	}
+
+	// the first row is the header
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i := 0; i < cols; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			b.WriteString(" " + cell + " |")
+		}
+	}
+	writeRow(rows[0])
+	b.WriteString("\n|")
+	b.WriteString(strings.Repeat(" --- |", cols))
+	for _, row := range rows[1:] {
+		b.WriteString("\n")
+		writeRow(row)
+	}
+	return b.String()
+}
+
+// renderInline renders an inline element (or a block found inside an inline element).
+// Line breaks are output as "\n", and handled when building paragraphs.
+func renderInline(b *strings.Builder, n *node) {
+	switch n.tag {
+	case "":
+		b.WriteString(escape(collapseSpaces(n.text)))
+	case "br":
+		b.WriteString("\n")
+	case "script", "style":
+	case "img":
+		b.WriteString("![" + escape(n.attr("alt")) + "](" + destination(n.attr("src")) + ")")
+	case "a":
+		text := inlineText(n)
+		href := n.attr("href")
+		switch {
+		case href == "":
+			b.WriteString(text)
+		case text == href && (strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://")):
+			b.WriteString("<" + href + ">")
+		default:
+			if strings.TrimSpace(text) == "" {
+				text = escape(href)
+			}
+			b.WriteString("[" + text + "](" + destination(href) + ")")
+		}
+	case "b", "strong":
+		b.WriteString(emphasis(inlineText(n), "**"))
+	case "i", "em", "cite", "dfn":
+		b.WriteString(emphasis(inlineText(n), "*"))
+	case "s", "strike", "del":
+		b.WriteString(emphasis(inlineText(n), "~~"))
+	case "u", "ins":
+		// no Markdown equivalent, but GitHub renders <ins>
+		b.WriteString(emphasis(inlineText(n), "<ins>", "</ins>"))
+	case "code", "tt", "kbd", "samp", "var":
+		b.WriteString(codeSpan(collapseSpaces(textContent(n))))
+	default:
+		if blockElements[n.tag] {
+			b.WriteString("\n")
+		}
+		for _, c := range n.children {
+			renderInline(b, c)
+		}
+		if blockElements[n.tag] {
+			b.WriteString("\n")
+		}
+	}
+}
+
+// inlineText renders the children of n as inline Markdown.
+func inlineText(n *node) string {
+	var b strings.Builder
+	for _, c := range n.children {
+		renderInline(&b, c)
+	}
+	return b.String()
+}
+
+// textContent returns the raw text of n, with line breaks between blocks.
+func textContent(n *node) string {
+	var b strings.Builder
+	var walk func(n *node)
+	walk = func(n *node) {
+		switch {
+		case n.tag == "":
+			b.WriteString(n.text)
+		case n.tag == "br":
+			b.WriteString("\n")
+		case blockElements[n.tag] && n.tag != "pre":
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+				b.WriteString("\n")
+			}
+			for _, c := range n.children {
+				walk(c)
+			}
+		default:
+			for _, c := range n.children {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return strings.Replace(b.String(), "\u00a0", " ", -1)
+}
+
+var (
+	spacesRegexp       = regexp.MustCompile(`[ \t\r\n\f\x{00a0}]+`)
+	doubleSpacesRegexp = regexp.MustCompile(` {2,}`)
+	orderedListRegexp  = regexp.MustCompile(`^(\d+)([.)]) `)
+	languageRegexp     = regexp.MustCompile(`(?:^|\s)(?:language|lang)-(\S+)`)
+)
+
+// collapseSpaces collapses the white space like browsers do (&nbsp; included).
+func collapseSpaces(s string) string {
+	return spacesRegexp.ReplaceAllString(s, " ")
+}
+
+// paragraphs splits the inline Markdown into paragraphs on empty lines.
+// Single line breaks are kept as hard line breaks.
+func paragraphs(s string) []string {
+	var paras []string
+	var cur []string
+	flush := func() {
+		if len(cur) > 0 {
+			paras = append(paras, strings.Join(cur, "  \n"))
+			cur = nil
+		}
+	}
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimSpace(doubleSpacesRegexp.ReplaceAllString(l, " "))
+		if l == "" {
+			flush()
+			continue
+		}
+		cur = append(cur, escapeLineStart(l))
+	}
+	flush()
+	return paras
+}
+
+// lines returns the non-empty lines of the inline Markdown.
+func lines(s string) []string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimSpace(doubleSpacesRegexp.ReplaceAllString(l, " "))
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// escape escapes the Markdown special characters of the text.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\', '`', '*', '[', ']', '<':
+			b.WriteByte('\\')
+		case '_':
+			// snake_case words are left untouched, since GitHub does not handle intraword emphasis with _
+			if i == 0 || i == len(s)-1 || !isWordByte(s[i-1]) || !isWordByte(s[i+1]) {
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// escapeLineStart escapes the text that would start a block (heading, list…) at the beginning of a line.
+func escapeLineStart(l string) string {
+	switch {
+	case strings.HasPrefix(l, "#"), strings.HasPrefix(l, ">"),
+		strings.HasPrefix(l, "- "), strings.HasPrefix(l, "+ "), l == "-", l == "---":
+		return `\` + l
+	case orderedListRegexp.MatchString(l):
+		return orderedListRegexp.ReplaceAllString(l, `$1\$2 `)
+	}
+	return l
+}
+
+// emphasis wraps the text with the markers, leaving the surrounding spaces outside.
+func emphasis(s string, markers ...string) string {
+	open, close := markers[0], markers[0]
+	if len(markers) > 1 {
+		close = markers[1]
+	}
+	ls := strings.Split(s, "\n")
+	for i, l := range ls {
+		t := strings.TrimSpace(l)
+		if t == "" {
+			continue
+		}
+		start := strings.Index(l, t)
+		ls[i] = l[:start] + open + t + close + l[start+len(t):]
+	}
+	return strings.Join(ls, "\n")
+}
+
+// codeSpan wraps the text in enough backticks.
+func codeSpan(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return s
+	}
+	ticks := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return ticks + s + ticks
+}
+
+// fence builds a fenced code block.
+func fence(s, marker, info string) string {
+	if n := longestRun(s, marker[0]); n >= len(marker) {
+		marker = strings.Repeat(marker[:1], n+1)
+	}
+	return marker + info + "\n" + s + "\n" + marker
+}
+
+// codeLanguage returns the language of a <pre> block, from its class (or the one of its <code> child).
+func codeLanguage(n *node) string {
+	class := n.attr("class")
+	if len(n.children) == 1 && n.children[0].tag == "code" {
+		class += " " + n.children[0].attr("class")
+	}
+	if m := languageRegexp.FindStringSubmatch(class); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// destination formats a link destination, using <…> when it holds spaces or parentheses.
+func destination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return url
+}
+
+// prefixLines adds the prefix to all the lines of s, or the empty prefix to empty lines.
+func prefixLines(s, prefix, empty string) string {
+	ls := strings.Split(s, "\n")
+	for i, l := range ls {
+		if l == "" {
+			ls[i] = empty + l
+		} else {
+			ls[i] = prefix + l
+		}
+	}
+	return strings.Join(ls, "\n")
+}
+
+func longestRun(s string, c byte) int {
+	longest, n := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			n++
+			if n > longest {
+				longest = n
+			}
+		} else {
+			n = 0
+		}
+	}
+	return longest
+}
+
+func isWordByte(b byte) bool {
+	return b >= 0x80 || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
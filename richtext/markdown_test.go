@@ -0,0 +1,98 @@
+package richtext_test
+
+import (
+	"testing"
+
+	"github.com/ushu/quiver/richtext"
+)
+
+func TestToMarkdown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		html string
+		want string
+	}{
+		// plain text, entities and white space
+		{"This is a text Cell.", "This is a text Cell."},
+		{"Fish&nbsp;&amp; chips&nbsp; &lt;3", "Fish & chips \\<3"},
+		{"  lots \n of\t space  ", "lots of space"},
+		// Quiver divs and line breaks
+		{"<div>One</div><div>Two</div>", "One  \nTwo"},
+		{"<div>One</div><div><br></div><div>Two<br></div>", "One\n\nTwo"},
+		{"<div>a</div><div>b</div><div><br></div><div>c</div>", "a  \nb\n\nc"},
+		{"Text<div>line</div><p>Para</p>", "Text  \nline\n\nPara"},
+		{"One<br>Two<br><br>Three", "One  \nTwo\n\nThree"},
+		// inline formatting
+		{
+			"Some <b>bold</b>, <i>italics</i>, <u>underlined</u> and <strike>dashed</strike> text.",
+			"Some **bold**, *italics*, <ins>underlined</ins> and ~~dashed~~ text.",
+		},
+		{"<b><i>both</i></b>,&nbsp;<b> spaced </b>!", "***both***, **spaced** !"},
+		{"Run <code>go test ./...</code> or <code>a`b</code>", "Run `go test ./...` or ``a`b``"},
+		{"2 * 3_000 = my_var [x]", `2 \* 3_000 = my_var \[x\]`},
+		// links and images
+		{`This is a link&nbsp;<a href="http://www.apple.com">http://www.apple.com</a>.`, "This is a link <http://www.apple.com>."},
+		{`<a href="quiver-note-url/ABC">Other note</a>`, "[Other note](quiver-note-url/ABC)"},
+		{`<a href="../My notes/a.md">a</a>`, "[a](<../My notes/a.md>)"},
+		{`<img src="quiver-image-url/A.jpg">`, "![](quiver-image-url/A.jpg)"},
+		{`<img src="b.png" alt="A *bold* cat"/>x`, `![A \*bold\* cat](b.png)x`},
+		// headings and escaped block markers
+		{"<h1>Title</h1><h3>Sub <i>title</i></h3>", "# Title\n\n### Sub *title*"},
+		{"<div># not a title</div><div>1. not a list</div>", "\\# not a title  \n1\\. not a list"},
+		// lists
+		{"<ul><li>One</li><li>Two<ul><li>Nested</li></ul></li></ul>", "- One\n- Two\n  - Nested"},
+		{"<ol><li>One<li>Two</ol>", "1. One\n2. Two"},
+		{`<ol start="3"><li>Three</li></ol>`, "3. Three"},
+		// tables
+		{
+			"<table><tr><th>Name</th><th>Value</th></tr><tr><td>a|b</td><td><b>1</b></td></tr><tr><td>c</td></tr></table>",
+			"| Name | Value |\n| --- | --- |\n| a\\|b | **1** |\n| c |  |",
+		},
+		// blocks
+		{"<blockquote>Quoted<br>text</blockquote>", "> Quoted  \n> text"},
+		{"<pre><code class=\"language-go\">if a &lt; b {\n\treturn\n}</code></pre>", "```go\nif a < b {\n\treturn\n}\n```"},
+		{"Before<hr>After", "Before\n\n---\n\nAfter"},
+		// junk
+		{"<!-- comment --><script>alert(1)</script><style>p{}</style><p>Text</p></span></div>", "Text"},
+		{"<div>Unclosed <b>bold", "Unclosed **bold**"},
+		{"a < b", `a \< b`},
+	}
+	for _, tt := range tests {
+		if got := richtext.ToMarkdown(tt.html); got != tt.want {
+			t.Errorf("ToMarkdown(%q) =\n%q\nwant\n%q", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestToMarkdownFixture(t *testing.T) {
+	t.Parallel()
+
+	// taken from the "Images, Files and Links" note of the test library
+	html := `<div>This is an inline image:&nbsp;<img src="quiver-image-url/1C3392AA.jpg">.</div><div><br></div>` +
+		`<div>This is another image:</div><div><br><div><img src="quiver-image-url/F6E1CA4A.png"><br></div></div>`
+	want := "This is an inline image: ![](quiver-image-url/1C3392AA.jpg).\n\n" +
+		"This is another image:\n\n" +
+		"![](quiver-image-url/F6E1CA4A.png)"
+	if got := richtext.ToMarkdown(html); got != want {
+		t.Errorf("ToMarkdown() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestToMarkdownFromText(t *testing.T) {
+	t.Parallel()
+
+	// the lines of plain text are kept, the empty ones separating the paragraphs
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"One\nTwo", "One  \nTwo"},
+		{"One\n\nTwo\nThree", "One\n\nTwo  \nThree"},
+	}
+	for _, tt := range tests {
+		if got := richtext.ToMarkdown(richtext.FromText(tt.text)); got != tt.want {
+			t.Errorf("ToMarkdown(FromText(%q)) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
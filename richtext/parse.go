@@ -0,0 +1,213 @@
+package richtext
+
+import (
+	"html"
+	"strings"
+)
+
+// node is an element (or a text) of a parsed HTML fragment.
+type node struct {
+	// The lowercased tag name, or "" for text nodes.
+	tag   string
+	attrs map[string]string
+	// The text, with its entities decoded, for text nodes.
+	text     string
+	children []*node
+	parent   *node
+}
+
+func (n *node) attr(name string) string {
+	return n.attrs[name]
+}
+
+// The elements that never have contents
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+	"link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// The elements which are closed by a sibling of the same kind, and their scope
+var impliedEnds = map[string][]string{
+	"li": {"ul", "ol"},
+	"dt": {"dl"},
+	"dd": {"dl"},
+	"tr": {"table", "thead", "tbody", "tfoot"},
+	"td": {"tr", "table"},
+	"th": {"tr", "table"},
+	"p":  {"div", "li", "td", "th", "blockquote"},
+}
+
+// parse builds the tree of the HTML fragment.
+// It is lenient, like the browsers: unknown end tags are ignored, and unclosed elements are closed at the end.
+func parse(s string) *node {
+	root := &node{tag: "#root"}
+	cur := root
+	add := func(n *node) {
+		n.parent = cur
+		cur.children = append(cur.children, n)
+	}
+	text := func(t string) {
+		if t == "" {
+			return
+		}
+		add(&node{text: html.UnescapeString(t)})
+	}
+
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			text(s)
+			break
+		}
+		text(s[:i])
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			// comment
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				return root
+			}
+			s = s[end+3:]
+		case strings.HasPrefix(s, "</"):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				text(s)
+				return root
+			}
+			name := strings.ToLower(strings.TrimSpace(s[2:end]))
+			s = s[end+1:]
+			// close the matching element, if open
+			for n := cur; n != root; n = n.parent {
+				if n.tag == name {
+					cur = n.parent
+					break
+				}
+			}
+		case len(s) > 1 && (isLetter(s[1]) || s[1] == '!' || s[1] == '?'):
+			n, rest, selfClosing, ok := parseTag(s)
+			if !ok {
+				text(s)
+				return root
+			}
+			s = rest
+			if n == nil {
+				// doctype & co.
+				continue
+			}
+			if scopes, ok := impliedEnds[n.tag]; ok {
+				cur = closeImplied(cur, n.tag, scopes)
+			}
+			add(n)
+			if n.tag == "script" || n.tag == "style" {
+				// raw text elements are skipped, with their contents
+				end := strings.Index(strings.ToLower(s), "</"+n.tag)
+				if end < 0 {
+					return root
+				}
+				s = s[end:]
+				cur = n
+			} else if !voidElements[n.tag] && !selfClosing {
+				cur = n
+			}
+		default:
+			text(s[:1])
+			s = s[1:]
+		}
+	}
+	return root
+}
+
+// closeImplied closes the open element with the given tag, if any, stopping at the given scopes.
+func closeImplied(cur *node, tag string, scopes []string) *node {
+	for n := cur; n.parent != nil; n = n.parent {
+		if n.tag == tag {
+			return n.parent
+		}
+		for _, s := range scopes {
+			if n.tag == s {
+				return cur
+			}
+		}
+	}
+	return cur
+}
+
+// parseTag parses the start tag at the beginning of s, and returns the new element (nil for <!…> and <?…>
+// declarations) along with the rest of the string, and whether the tag is self-closing (<tag/>).
+func parseTag(s string) (n *node, rest string, selfClosing bool, ok bool) {
+	if s[1] == '!' || s[1] == '?' {
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			return nil, "", false, false
+		}
+		return nil, s[end+1:], false, true
+	}
+
+	i := 1
+	for i < len(s) && !isSpace(s[i]) && s[i] != '>' && s[i] != '/' {
+		i++
+	}
+	n = &node{tag: strings.ToLower(s[1:i]), attrs: make(map[string]string)}
+
+	for i < len(s) {
+		for i < len(s) && (isSpace(s[i]) || s[i] == '/') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, "", false, false
+		}
+		if s[i] == '>' {
+			return n, s[i+1:], s[i-1] == '/', true
+		}
+
+		// attribute name
+		start := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) || s[i] != '=' {
+			n.attrs[name] = ""
+			continue
+		}
+		i++
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return nil, "", false, false
+		}
+
+		// attribute value, quoted or not
+		var value string
+		if q := s[i]; q == '"' || q == '\'' {
+			end := strings.IndexByte(s[i+1:], q)
+			if end < 0 {
+				return nil, "", false, false
+			}
+			value = s[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			start := i
+			for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+				i++
+			}
+			value = s[start:i]
+		}
+		n.attrs[name] = html.UnescapeString(value)
+	}
+	return nil, "", false, false
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
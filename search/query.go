@@ -0,0 +1,152 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ushu/quiver"
+)
+
+// Query is a parsed search query.
+//
+// The query syntax is a list of space-separated words, all of which should match (in the title, the tags or the
+// cells of a note), mixed with filters:
+//
+//	tag:TAG                 the note has the given tag
+//	notebook:NAME           the note belongs to the notebook with the given name (or UUID)
+//	type:TYPE               the note has at least one cell of the given type (code, text, markdown, latex, diagram)
+//	lang:LANGUAGE           the note has at least one code cell in the given language
+//	created:FROM..TO        the note was created between the two dates (YYYY-MM-DD, inclusive)
+//	updated:FROM..TO        the note was updated between the two dates (YYYY-MM-DD, inclusive)
+//
+// Words and filter values are case insensitive, and can be quoted to hold spaces (tag:"open source").
+// Each bound of a date range can be omitted ("created:2020-01-01.." or "updated:..2021-12-31"), and a single
+// date ("created:2020-01-01") matches the whole day.
+type Query struct {
+	// The words to look for.
+	Terms []string
+	// The filters, all of which must match.
+	Tags      []string
+	Notebooks []string
+	Types     []quiver.CellType
+	Languages []string
+	Created   DateRange
+	Updated   DateRange
+}
+
+// DateRange is a range of time, with optional bounds.
+type DateRange struct {
+	// The start of the range (inclusive), or the zero time when unbounded.
+	From time.Time
+	// The end of the range (exclusive), or the zero time when unbounded.
+	To time.Time
+}
+
+// Contains tells if t is in the range.
+func (r DateRange) Contains(t time.Time) bool {
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !t.Before(r.To) {
+		return false
+	}
+	return true
+}
+
+const dateLayout = "2006-01-02"
+
+// ParseQuery parses a query string, see Query for the syntax.
+func ParseQuery(s string) (*Query, error) {
+	q := new(Query)
+	for _, w := range splitQuery(s) {
+		i := strings.IndexByte(w, ':')
+		if i < 0 {
+			q.Terms = append(q.Terms, tokenize(w)...)
+			continue
+		}
+
+		key, value := strings.ToLower(w[:i]), strings.Trim(w[i+1:], `"`)
+		if value == "" {
+			return nil, fmt.Errorf("missing value for %q filter", key)
+		}
+		var err error
+		switch key {
+		case "tag":
+			q.Tags = append(q.Tags, strings.ToLower(value))
+		case "notebook":
+			q.Notebooks = append(q.Notebooks, strings.ToLower(value))
+		case "type":
+			t := quiver.CellType(strings.ToLower(value))
+			switch t {
+			case quiver.CodeCell, quiver.TextCell, quiver.MarkdownCell, quiver.LatexCell, quiver.DiagramCell:
+				q.Types = append(q.Types, t)
+			default:
+				return nil, fmt.Errorf("unknown cell type %q", value)
+			}
+		case "lang":
+			q.Languages = append(q.Languages, strings.ToLower(value))
+		case "created":
+			q.Created, err = parseDateRange(value)
+		case "updated":
+			q.Updated, err = parseDateRange(value)
+		default:
+			// not a filter: "http://…", "c++:" and such are searched as plain words
+			q.Terms = append(q.Terms, tokenize(w)...)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// splitQuery splits the query on spaces, except inside double quotes.
+func splitQuery(s string) []string {
+	var words []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			cur.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if cur.Len() > 0 {
+				words = append(words, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		words = append(words, cur.String())
+	}
+	return words
+}
+
+func parseDateRange(s string) (DateRange, error) {
+	var r DateRange
+	from, to := s, s
+	if i := strings.Index(s, ".."); i >= 0 {
+		from, to = s[:i], s[i+2:]
+	}
+
+	var err error
+	if from != "" {
+		r.From, err = time.ParseInLocation(dateLayout, from, time.Local)
+		if err != nil {
+			return r, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", from)
+		}
+	}
+	if to != "" {
+		r.To, err = time.ParseInLocation(dateLayout, to, time.Local)
+		if err != nil {
+			return r, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", to)
+		}
+		// the end date is inclusive
+		r.To = r.To.AddDate(0, 0, 1)
+	}
+	return r, nil
+}
@@ -0,0 +1,332 @@
+/*
+Package search implements a full-text search engine over the contents of a Quiver library.
+
+An Index is built once from a loaded library, and then answers ranked queries:
+
+	lib, _ := quiver.ReadLibrary("/path/to/Quiver.qvlibrary", false)
+	idx := search.NewIndex(lib)
+
+	results, _ := idx.Search(`goroutine tag:golang type:code updated:2020-01-01..`)
+	for _, r := range results {
+		fmt.Println(r.Note.Title, r.Snippet)
+	}
+
+See Query for the query syntax.
+*/
+package search
+
+import (
+	"html"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ushu/quiver"
+)
+
+// The weights of the different fields of a note when ranking results
+const (
+	titleWeight = 3
+	tagWeight   = 2
+	bodyWeight  = 1
+)
+
+// The number of characters displayed around a match in snippets
+const snippetRadius = 60
+
+// Index is an inverted index over the titles, tags and cells of all the notes of a library.
+type Index struct {
+	docs     []*document
+	postings map[string][]posting
+}
+
+// document is an indexed note
+type document struct {
+	note     *quiver.Note
+	notebook *quiver.Notebook
+	// the plain text of all the cells, used for snippets
+	body string
+	// the lowercased tags, cell types and languages, used for filters
+	tags      map[string]bool
+	types     map[quiver.CellType]bool
+	languages map[string]bool
+}
+
+// posting holds the (weighted) number of occurrences of a term in a document
+type posting struct {
+	doc   int
+	count int
+}
+
+// Result is a note matching a query.
+type Result struct {
+	// The matching note.
+	Note *quiver.Note
+	// The notebook holding the note.
+	Notebook *quiver.Notebook
+	// The relevance of the note: the higher, the better.
+	Score float64
+	// An extract of the note contents around the first match.
+	Snippet string
+}
+
+// NewIndex indexes all the notes of the library.
+func NewIndex(lib *quiver.Library) *Index {
+	idx := &Index{postings: make(map[string][]posting)}
+	for _, nb := range lib.Notebooks {
+		for _, n := range nb.Notes {
+			idx.add(nb, n)
+		}
+	}
+	return idx
+}
+
+// Len returns the number of indexed notes.
+func (idx *Index) Len() int {
+	return len(idx.docs)
+}
+
+func (idx *Index) add(nb *quiver.Notebook, n *quiver.Note) {
+	if n.NoteMetadata == nil {
+		return
+	}
+	doc := &document{
+		note:      n,
+		notebook:  nb,
+		tags:      make(map[string]bool, len(n.Tags)),
+		types:     make(map[quiver.CellType]bool),
+		languages: make(map[string]bool),
+	}
+
+	counts := make(map[string]int)
+	for _, t := range tokenize(n.Title) {
+		counts[t] += titleWeight
+	}
+	for _, tag := range n.Tags {
+		doc.tags[strings.ToLower(tag)] = true
+		for _, t := range tokenize(tag) {
+			counts[t] += tagWeight
+		}
+	}
+
+	var body []string
+	if n.NoteContent != nil {
+		for _, c := range n.Cells {
+			doc.types[c.Type] = true
+			if c.IsCode() && c.Language != "" {
+				doc.languages[strings.ToLower(c.Language)] = true
+			}
+			text := cellText(c)
+			body = append(body, text)
+			for _, t := range tokenize(text) {
+				counts[t] += bodyWeight
+			}
+		}
+	}
+	doc.body = strings.Join(body, " ")
+
+	id := len(idx.docs)
+	idx.docs = append(idx.docs, doc)
+	for t, c := range counts {
+		idx.postings[t] = append(idx.postings[t], posting{id, c})
+	}
+}
+
+// Search parses the query and returns the matching notes, best matches first.
+// See Query for the syntax.
+func (idx *Index) Search(query string) ([]*Result, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return idx.SearchQuery(q), nil
+}
+
+// SearchQuery returns the notes matching the query, best matches first.
+// When the query has no terms, all the notes matching the filters are returned, most recently updated first.
+func (idx *Index) SearchQuery(q *Query) []*Result {
+	scores := make(map[int]float64)
+	if len(q.Terms) == 0 {
+		for i := range idx.docs {
+			scores[i] = 0
+		}
+	} else {
+		// all the terms should match
+		terms := uniqueTerms(q.Terms)
+		matches := make(map[int]int)
+		n := float64(len(idx.docs))
+		for _, t := range terms {
+			postings := idx.postings[t]
+			idf := math.Log(1 + n/float64(len(postings)+1))
+			for _, p := range postings {
+				matches[p.doc]++
+				c := float64(p.count)
+				scores[p.doc] += idf * c / (c + 1.2)
+			}
+		}
+		for doc, m := range matches {
+			if m < len(terms) {
+				delete(scores, doc)
+			}
+		}
+	}
+
+	results := make([]*Result, 0, len(scores))
+	for i, score := range scores {
+		doc := idx.docs[i]
+		if !doc.matches(q) {
+			continue
+		}
+		results = append(results, &Result{
+			Note:     doc.note,
+			Notebook: doc.notebook,
+			Score:    score,
+			Snippet:  snippet(doc.body, q.Terms),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		ti, tj := time.Time(results[i].Note.UpdatedAt), time.Time(results[j].Note.UpdatedAt)
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return results[i].Note.UUID < results[j].Note.UUID
+	})
+	return results
+}
+
+// matches checks the filters of the query
+func (doc *document) matches(q *Query) bool {
+	for _, t := range q.Tags {
+		if !doc.tags[t] {
+			return false
+		}
+	}
+	for _, name := range q.Notebooks {
+		if doc.notebook.NotebookMetadata == nil {
+			return false
+		}
+		if strings.ToLower(doc.notebook.Name) != name && strings.ToLower(doc.notebook.UUID) != name {
+			return false
+		}
+	}
+	for _, t := range q.Types {
+		if !doc.types[t] {
+			return false
+		}
+	}
+	for _, l := range q.Languages {
+		if !doc.languages[l] {
+			return false
+		}
+	}
+	return q.Created.Contains(time.Time(doc.note.CreatedAt)) && q.Updated.Contains(time.Time(doc.note.UpdatedAt))
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	unique := terms[:0:0]
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			unique = append(unique, t)
+		}
+	}
+	return unique
+}
+
+// tokenize splits the text into lowercased words.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+var (
+	htmlBlockRegexp  = regexp.MustCompile(`(?i)</?(?:div|br|p|li|ul|ol|h[1-6]|table|tr|td|th|pre|blockquote)\b[^>]*>`)
+	htmlTagRegexp    = regexp.MustCompile(`<[^>]*>`)
+	whitespaceRegexp = regexp.MustCompile(`\s+`)
+)
+
+// cellText returns the plain text of the cell.
+func cellText(c *quiver.Cell) string {
+	s := c.Data
+	if c.IsText() {
+		// text cells hold HTML: blocks are separated by spaces, while inline tags are dropped
+		s = htmlBlockRegexp.ReplaceAllString(s, " ")
+		s = htmlTagRegexp.ReplaceAllString(s, "")
+		s = html.UnescapeString(s)
+	}
+	return strings.TrimSpace(whitespaceRegexp.ReplaceAllString(s, " "))
+}
+
+// snippet extracts the text around the first occurrence of one of the terms.
+func snippet(body string, terms []string) string {
+	if body == "" {
+		return ""
+	}
+
+	// find the first match, as a whole word
+	start := -1
+	lower := strings.ToLower(body)
+	if len(lower) != len(body) {
+		// some runes changed size: offsets cannot be shared
+		terms = nil
+	}
+	for _, t := range terms {
+		for off := 0; off < len(lower); {
+			i := strings.Index(lower[off:], t)
+			if i < 0 {
+				break
+			}
+			i += off
+			if isWordBoundary(lower, i, i+len(t)) {
+				if start < 0 || i < start {
+					start = i
+				}
+				break
+			}
+			off = i + len(t)
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	// and cut the text around it, on rune boundaries
+	from, to := start-snippetRadius, start+snippetRadius
+	prefix, suffix := "…", "…"
+	if from <= 0 {
+		from, prefix = 0, ""
+	}
+	if to >= len(body) {
+		to, suffix = len(body), ""
+	}
+	for from > 0 && !isRuneStart(body[from]) {
+		from--
+	}
+	for to < len(body) && !isRuneStart(body[to]) {
+		to++
+	}
+	return prefix + strings.TrimSpace(body[from:to]) + suffix
+}
+
+func isWordBoundary(s string, from, to int) bool {
+	before := from == 0 || !isWordByte(s[from-1])
+	after := to == len(s) || !isWordByte(s[to])
+	return before && after
+}
+
+func isWordByte(b byte) bool {
+	return b >= 0x80 || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z')
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
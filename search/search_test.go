@@ -0,0 +1,131 @@
+package search_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ushu/quiver"
+	"github.com/ushu/quiver/search"
+)
+
+func loadIndex(t *testing.T) *search.Index {
+	t.Helper()
+	lib, err := quiver.ReadLibrary(filepath.Join("..", "testdata", "Quiver.qvlibrary"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return search.NewIndex(lib)
+}
+
+func titles(results []*search.Result) []string {
+	titles := make([]string, len(results))
+	for i, r := range results {
+		titles[i] = r.Note.Title
+	}
+	return titles
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+	idx := loadIndex(t)
+	if idx.Len() != 3 {
+		t.Fatalf("idx.Len() = %v; want %v", idx.Len(), 3)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// words, case insensitive, in titles, tags and cells
+		{"bold", []string{"Text cells"}},
+		{"TAGS", []string{"Tags"}},
+		{"dashed", []string{"Text cells"}},
+		{"cell italics", []string{"Text cells"}},
+		{"missing", []string{}},
+		// HTML is not indexed, but entities are decoded
+		{"href", []string{}},
+		// filters
+		{"tag:tutorial", []string{"Text cells"}},
+		{"cell tag:test", []string{"Tags"}},
+		{"notebook:\"quiver test\" linked", []string{"Images, Files and Links"}},
+		{"notebook:other", []string{}},
+		{"type:text created:2014-01-01..2014-12-31", []string{"Text cells", "Images, Files and Links"}},
+		{"type:code", []string{}},
+		{"updated:2017-09-17..2017-09-19", []string{"Tags", "Text cells", "Images, Files and Links"}},
+	}
+	for _, tt := range tests {
+		results, err := idx.Search(tt.query)
+		if err != nil {
+			t.Errorf("idx.Search(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if got := titles(results); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("idx.Search(%q) = %q; want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	t.Parallel()
+	idx := loadIndex(t)
+
+	// title matches rank first
+	results, err := idx.Search("tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].Note.Title != "Tags" {
+		t.Fatalf("idx.Search(%q) = %q; want %q first", "tags", titles(results), "Tags")
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	t.Parallel()
+	idx := loadIndex(t)
+
+	results, err := idx.Search("underlined")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("len(results) = %v; want %v", len(results), 1)
+	}
+	if s := results[0].Snippet; !strings.Contains(s, "underlined") || strings.Contains(s, "<") {
+		t.Errorf("results[0].Snippet = %q", s)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	t.Parallel()
+
+	q, err := search.ParseQuery(`Hello tag:"open source" lang:Go type:code created:2020-01-01.. updated:..2020-12-31 http://x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"hello", "http", "x"}; strings.Join(q.Terms, "|") != strings.Join(want, "|") {
+		t.Errorf("q.Terms = %q; want %q", q.Terms, want)
+	}
+	if len(q.Tags) != 1 || q.Tags[0] != "open source" {
+		t.Errorf("q.Tags = %q; want %q", q.Tags, "open source")
+	}
+	if len(q.Languages) != 1 || q.Languages[0] != "go" {
+		t.Errorf("q.Languages = %q; want %q", q.Languages, "go")
+	}
+	if len(q.Types) != 1 || q.Types[0] != quiver.CodeCell {
+		t.Errorf("q.Types = %q; want %q", q.Types, quiver.CodeCell)
+	}
+	if !q.Created.Contains(time.Date(2030, 1, 1, 0, 0, 0, 0, time.Local)) || q.Created.Contains(time.Date(2019, 12, 31, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("q.Created = %v", q.Created)
+	}
+	if !q.Updated.Contains(time.Date(2020, 12, 31, 23, 0, 0, 0, time.Local)) || q.Updated.Contains(time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("q.Updated = %v", q.Updated)
+	}
+
+	for _, bad := range []string{"type:image", "created:yesterday", "tag:"} {
+		if _, err := search.ParseQuery(bad); err == nil {
+			t.Errorf("ParseQuery(%q) should fail", bad)
+		}
+	}
+}
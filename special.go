@@ -0,0 +1,36 @@
+package quiver
+
+// The UUIDs of the special notebooks created by the Quiver app.
+//
+// Unlike the other notebooks, they are stored in the "Inbox.qvnotebook" and "Trash.qvnotebook" directories, and
+// usually don't appear in the hierarchy of the library (LibraryMetadata.Children).
+const (
+	InboxUUID = "Inbox"
+	TrashUUID = "Trash"
+)
+
+// IsInbox returns true if the Notebook is the Inbox of the library.
+func (n *Notebook) IsInbox() bool {
+	return n.NotebookMetadata != nil && n.UUID == InboxUUID
+}
+
+// IsTrash returns true if the Notebook is the Trash of the library, which holds the deleted notes.
+func (n *Notebook) IsTrash() bool {
+	return n.NotebookMetadata != nil && n.UUID == TrashUUID
+}
+
+// Inbox returns the Inbox notebook of the library, or nil if not found.
+func (m *Library) Inbox() *Notebook {
+	return m.NotebookByUUID(InboxUUID)
+}
+
+// Trash returns the Trash notebook of the library, or nil if not found (or excluded with ReadOptions.ExcludeTrash).
+func (m *Library) Trash() *Notebook {
+	return m.NotebookByUUID(TrashUUID)
+}
+
+// IsTrashed returns true if the note is in the Trash of the library.
+func (m *Library) IsTrashed(n *Note) bool {
+	nb := m.NotebookOf(n)
+	return nb != nil && nb.IsTrash()
+}
@@ -0,0 +1,117 @@
+package quiver_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ushu/quiver"
+)
+
+var specialLibrary = fstest.MapFS{
+	"Special.qvlibrary/meta.json":                              {Data: []byte(`{"children": [{"uuid": "NB", "children": [{"uuid": "GONE"}]}]}`)},
+	"Special.qvlibrary/NB.qvnotebook/meta.json":                {Data: []byte(`{"name": "Notebook", "uuid": "NB"}`)},
+	"Special.qvlibrary/NB.qvnotebook/N.qvnote/meta.json":       {Data: []byte(`{"title": "N", "uuid": "N"}`)},
+	"Special.qvlibrary/NB.qvnotebook/N.qvnote/content.json":    {Data: []byte(`{"cells": []}`)},
+	"Special.qvlibrary/Inbox.qvnotebook/meta.json":             {Data: []byte(`{"name": "Inbox", "uuid": "Inbox"}`)},
+	"Special.qvlibrary/Inbox.qvnotebook/I.qvnote/meta.json":    {Data: []byte(`{"title": "I", "uuid": "I"}`)},
+	"Special.qvlibrary/Inbox.qvnotebook/I.qvnote/content.json": {Data: []byte(`{"cells": []}`)},
+	"Special.qvlibrary/Trash.qvnotebook/meta.json":             {Data: []byte(`{"name": "Trash", "uuid": "Trash"}`)},
+	"Special.qvlibrary/Trash.qvnotebook/T.qvnote/meta.json":    {Data: []byte(`{"title": "T", "uuid": "T"}`)},
+	"Special.qvlibrary/Trash.qvnotebook/T.qvnote/content.json": {Data: []byte(`{"cells": []}`)},
+}
+
+func TestSpecialNotebooks(t *testing.T) {
+	t.Parallel()
+
+	lib, err := quiver.ReadLibraryFS(specialLibrary, "Special.qvlibrary", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lib.Notebooks) != 3 {
+		t.Fatalf("len(lib.Notebooks) = %v; want %v", len(lib.Notebooks), 3)
+	}
+	if nb := lib.Inbox(); nb == nil || !nb.IsInbox() || nb.IsTrash() {
+		t.Errorf("lib.Inbox() = %v; want the Inbox", nb)
+	}
+	if nb := lib.Trash(); nb == nil || !nb.IsTrash() || nb.IsInbox() {
+		t.Errorf("lib.Trash() = %v; want the Trash", nb)
+	}
+	if !lib.IsTrashed(lib.NoteByUUID("T")) || lib.IsTrashed(lib.NoteByUUID("N")) {
+		t.Error("only the T note should be trashed")
+	}
+}
+
+func TestExcludeTrash(t *testing.T) {
+	t.Parallel()
+
+	opts := &quiver.ReadOptions{ExcludeTrash: true}
+	lib, err := quiver.ReadLibraryFSContext(context.Background(), specialLibrary, "Special.qvlibrary", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lib.Trash() != nil || lib.NoteByUUID("T") != nil {
+		t.Error("the Trash should not be loaded")
+	}
+	if lib.Inbox() == nil || lib.NoteByUUID("I") == nil {
+		t.Error("the Inbox should be loaded")
+	}
+
+	var titles []string
+	err = quiver.WalkFS(specialLibrary, "Special.qvlibrary", opts, func(nb *quiver.NotebookMetadata, n *quiver.Note) error {
+		titles = append(titles, n.Title)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range titles {
+		if title == "T" {
+			t.Errorf("Walk visited the trashed note")
+		}
+	}
+}
+
+func TestWalkNotebooksHierarchySpecial(t *testing.T) {
+	t.Parallel()
+
+	lib, err := quiver.ReadLibraryFS(specialLibrary, "Special.qvlibrary", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the missing GONE notebook is skipped, and the special ones come last at the root
+	seen := make(map[string]int)
+	err = lib.WalkNotebooksHierarchy(func(nb *quiver.Notebook, parents []*quiver.Notebook) error {
+		if nb == nil {
+			t.Fatal("nil notebook")
+		}
+		if (nb.IsInbox() || nb.IsTrash()) && len(parents) != 0 {
+			t.Errorf("%v has parents %v", nb.UUID, parents)
+		}
+		seen[nb.UUID]++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, uuid := range []string{"NB", quiver.InboxUUID, quiver.TrashUUID} {
+		if seen[uuid] != 1 {
+			t.Errorf("notebook %v visited %v times; want 1", uuid, seen[uuid])
+		}
+	}
+
+	// a library without meta.json
+	lib, err = quiver.ReadLibrary(fixturePath("Quiver.qvlibrary"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	err = lib.WalkNotebooksHierarchy(func(nb *quiver.Notebook, parents []*quiver.Notebook) error {
+		count++
+		return nil
+	})
+	if err != nil || count != len(lib.Notebooks) {
+		t.Errorf("WalkNotebooksHierarchy visited %v notebooks (err = %v); want %v", count, err, len(lib.Notebooks))
+	}
+}
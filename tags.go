@@ -0,0 +1,146 @@
+package quiver
+
+import (
+	"path"
+	"sort"
+)
+
+// TagCount holds a tag, and the number of notes it is attached to.
+type TagCount struct {
+	// The tag.
+	Name string
+	// The number of notes with this tag.
+	Count int
+}
+
+// Tags returns all the tags used in the library with their note count, sorted by name.
+func (m *Library) Tags() []TagCount {
+	tags := m.lookup().tags
+	counts := make([]TagCount, 0, len(tags))
+	for t, notes := range tags {
+		counts = append(counts, TagCount{t, len(notes)})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
+
+// RenameTag renames the tag on all the notes, and saves their "meta.json" files.
+// It returns the number of updated notes. See MergeTags for details.
+func (m *Library) RenameTag(oldName, newName string) (int, error) {
+	return m.MergeTags(newName, oldName)
+}
+
+// MergeTags replaces all the from tags by the into tag on all the notes, and saves their "meta.json" files.
+// It returns the number of updated notes.
+//
+// The notes should have been loaded from an OS path, otherwise ErrReadOnly is returned. Notes are saved one at a
+// time: on error, the notes saved so far keep their new tags.
+func (m *Library) MergeTags(into string, from ...string) (int, error) {
+	merged := make(map[string]bool, len(from))
+	for _, t := range from {
+		merged[t] = true
+	}
+
+	return m.updateTags(func(tags []string) []string {
+		updated := make([]string, 0, len(tags))
+		seen := make(map[string]bool, len(tags))
+		for _, t := range tags {
+			if merged[t] {
+				t = into
+			}
+			if !seen[t] {
+				seen[t] = true
+				updated = append(updated, t)
+			}
+		}
+		return updated
+	})
+}
+
+// DeleteTag removes the tag from all the notes, and saves their "meta.json" files.
+// It returns the number of updated notes. See MergeTags for details.
+func (m *Library) DeleteTag(tag string) (int, error) {
+	return m.updateTags(func(tags []string) []string {
+		updated := make([]string, 0, len(tags))
+		for _, t := range tags {
+			if t != tag {
+				updated = append(updated, t)
+			}
+		}
+		return updated
+	})
+}
+
+// updateTags applies f to the tags of all the notes, and saves the notes whose tags changed.
+func (m *Library) updateTags(f func(tags []string) []string) (int, error) {
+	// rebuild the lookup tables in any case, since some notes may be updated before an error
+	defer m.Reindex()
+
+	count := 0
+	for _, nb := range m.Notebooks {
+		for _, n := range nb.Notes {
+			if n.NoteMetadata == nil {
+				continue
+			}
+			tags := f(n.Tags)
+			if stringsEqual(tags, n.Tags) {
+				continue
+			}
+
+			// (the note is only updated once saved)
+			meta := *n.NoteMetadata
+			meta.Tags = tags
+			err := n.writeMetadata(&meta)
+			if err != nil {
+				return count, err
+			}
+			n.Tags = tags
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// SaveMetadata saves the metadata of the note back into its "meta.json" file.
+// The note should have been loaded from an OS path, otherwise ErrReadOnly is returned.
+func (n *Note) SaveMetadata() error {
+	return n.writeMetadata(n.NoteMetadata)
+}
+
+// writeMetadata saves the given metadata into the "meta.json" file of the note.
+func (n *Note) writeMetadata(m *NoteMetadata) error {
+	p, err := osPath(n.fsys, path.Join(n.path, "meta.json"))
+	if err != nil {
+		return err
+	}
+	return WriteNoteMetadata(p, m)
+}
+
+// SaveContent saves the content of the note back into its "content.json" file.
+// The note should have been loaded from an OS path, otherwise ErrReadOnly is returned.
+func (n *Note) SaveContent() error {
+	p, err := osPath(n.fsys, path.Join(n.path, "content.json"))
+	if err != nil {
+		return err
+	}
+	content := n.NoteContent
+	if content == nil {
+		content = &NoteContent{}
+	}
+	return WriteNoteContent(p, n.Title, content)
+}
+
+func stringsEqual(l, r []string) bool {
+	if len(l) != len(r) {
+		return false
+	}
+	for i := range l {
+		if l[i] != r[i] {
+			return false
+		}
+	}
+	return true
+}
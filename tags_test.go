@@ -0,0 +1,94 @@
+package quiver_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ushu/quiver"
+)
+
+func TestLibraryTags(t *testing.T) {
+	t.Parallel()
+	lib, err := quiver.ReadLibrary(fixturePath("Quiver.qvlibrary"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := lib.Tags()
+	want := []quiver.TagCount{{"retest", 1}, {"tags", 1}, {"test", 1}, {"tutorial", 1}}
+	if len(tags) != len(want) {
+		t.Fatalf("lib.Tags() = %v; want %v", tags, want)
+	}
+	for i := range want {
+		if tags[i] != want[i] {
+			t.Errorf("lib.Tags()[%v] = %v; want %v", i, tags[i], want[i])
+		}
+	}
+}
+
+func TestLibraryTagOperations(t *testing.T) {
+	t.Parallel()
+	libPath := copyFixture(t, "Quiver.qvlibrary")
+	const UUID = "73385592-0CAB-41E5-9045-AEC528C2915A"
+
+	lib, err := quiver.ReadLibrary(libPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rename
+	n, err := lib.RenameTag("retest", "golang")
+	if err != nil || n != 1 {
+		t.Fatalf("lib.RenameTag() = %v, %v; want 1 note", n, err)
+	}
+	// Merge, which removes duplicates
+	n, err = lib.MergeTags("go", "golang", "test", "tutorial")
+	if err != nil || n != 2 {
+		t.Fatalf("lib.MergeTags() = %v, %v; want 2 notes", n, err)
+	}
+	// Delete
+	n, err = lib.DeleteTag("tags")
+	if err != nil || n != 1 {
+		t.Fatalf("lib.DeleteTag() = %v, %v; want 1 note", n, err)
+	}
+	if len(lib.NotesByTag("go")) != 2 {
+		t.Errorf("len(lib.NotesByTag(%q)) = %v; want %v", "go", len(lib.NotesByTag("go")), 2)
+	}
+
+	// The changes should be saved on disk
+	saved, err := quiver.ReadLibrary(libPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := saved.NoteByUUID(UUID).Tags
+	if want := []string{"go"}; !stringSliceEqual(tags, want) {
+		t.Errorf("saved tags = %q; want %q", tags, want)
+	}
+}
+
+func TestLibraryTagOperationsReadOnly(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"RO.qvlibrary/NB.qvnotebook/meta.json":             {Data: []byte(`{"name": "Notebook", "uuid": "NB"}`)},
+		"RO.qvlibrary/NB.qvnotebook/N.qvnote/meta.json":    {Data: []byte(`{"title": "N", "uuid": "N", "tags": ["go"]}`)},
+		"RO.qvlibrary/NB.qvnotebook/N.qvnote/content.json": {Data: []byte(`{"cells": []}`)},
+	}
+	lib, err := quiver.ReadLibraryFS(fsys, "RO.qvlibrary", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = lib.DeleteTag("go")
+	if !errors.Is(err, quiver.ErrReadOnly) {
+		t.Errorf("err = %v; want %v", err, quiver.ErrReadOnly)
+	}
+
+	// the notes which could not be saved are left unchanged
+	if n := lib.NoteByUUID("N"); len(n.Tags) != 1 || n.Tags[0] != "go" {
+		t.Errorf("n.Tags = %q; want %q", n.Tags, []string{"go"})
+	}
+	if len(lib.NotesByTag("go")) != 1 {
+		t.Errorf("len(lib.NotesByTag(%q)) = %v; want %v", "go", len(lib.NotesByTag("go")), 1)
+	}
+}
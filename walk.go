@@ -0,0 +1,65 @@
+package quiver
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// WalkFunc is the type of the function called by Walk for each note of a library.
+//
+// If the function returns SkipNotebook, the remaining notes of the current notebook are skipped.
+// Any other error stops the walk, and is returned by Walk.
+type WalkFunc func(nb *NotebookMetadata, n *Note) error
+
+// SkipNotebook is used as a return value from a WalkFunc to skip the remaining notes of the current notebook.
+var SkipNotebook = errors.New("skip this notebook")
+
+// Walk loads the notes of the Quiver library at the given path one at a time, as configured by opts, and calls
+// fn for each one of them.
+//
+// Unlike ReadLibrary, it never holds more than one note in memory, which allows to process very large libraries.
+// The notes are visited in the same order as ReadLibrary. In lenient mode, warnings are only reported through
+// opts.OnWarning.
+func Walk(path string, opts *ReadOptions, fn WalkFunc) error {
+	fsys, root := dirFS(path)
+	return WalkFS(fsys, root, opts, fn)
+}
+
+// WalkFS loads the notes of the Quiver library found at the given root in fsys one at a time, and calls fn for each
+// one of them. See Walk for details.
+func WalkFS(fsys fs.FS, root string, opts *ReadOptions, fn WalkFunc) error {
+	w := newWarnings(fsys, opts)
+	_, paths, err := readLibraryDir(fsys, root, opts, w)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		nbm, notePaths, err := readNotebookDir(fsys, p, w)
+		if err != nil {
+			if w.skip(p, err) {
+				continue
+			}
+			return err
+		}
+
+		for _, np := range notePaths {
+			n, err := ReadNoteFSWithOptions(fsys, np, opts)
+			if err != nil {
+				if w.skip(np, err) {
+					continue
+				}
+				return err
+			}
+			err = fn(nbm, n)
+			if err == SkipNotebook {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package quiver_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ushu/quiver"
+)
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+	libPath := fixturePath("Quiver.qvlibrary")
+
+	lib, err := quiver.ReadLibrary(libPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The notes should be visited in the same order as ReadLibrary
+	var uuids []string
+	err = quiver.Walk(libPath, nil, func(nb *quiver.NotebookMetadata, n *quiver.Note) error {
+		if nb.UUID != "FIXTURE" {
+			t.Errorf("nb.UUID = %q; want %q", nb.UUID, "FIXTURE")
+		}
+		uuids = append(uuids, n.UUID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for _, n := range lib.Notebooks[0].Notes {
+		want = append(want, n.UUID)
+	}
+	if !stringSliceEqual(uuids, want) {
+		t.Errorf("uuids = %q; want %q", uuids, want)
+	}
+}
+
+func TestWalkSkipNotebook(t *testing.T) {
+	t.Parallel()
+
+	count := 0
+	err := quiver.Walk(fixturePath("Quiver.qvlibrary"), nil, func(nb *quiver.NotebookMetadata, n *quiver.Note) error {
+		count++
+		return quiver.SkipNotebook
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("count = %v; want %v", count, 1)
+	}
+}
+
+func TestWalkError(t *testing.T) {
+	t.Parallel()
+	stop := errors.New("stop")
+
+	err := quiver.Walk(fixturePath("Quiver.qvlibrary"), nil, func(nb *quiver.NotebookMetadata, n *quiver.Note) error {
+		return stop
+	})
+	if err != stop {
+		t.Errorf("err = %v; want %v", err, stop)
+	}
+}
@@ -0,0 +1,85 @@
+package quiver
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+// Warning describes an element skipped while loading a library in lenient mode (see ReadOptions.Lenient).
+type Warning struct {
+	// The path of the skipped element: its OS path when loaded from one, its name in the fs.FS otherwise.
+	Path string
+	// Why the element was skipped.
+	Reason string
+	// The error that caused the element to be skipped, if any.
+	Err error
+}
+
+// String returns a human readable version of the warning.
+func (w Warning) String() string {
+	return fmt.Sprintf("%v: %v", w.Path, w.Reason)
+}
+
+// warnings collects the warnings emitted while loading in lenient mode.
+// It is safe for concurrent use.
+type warnings struct {
+	// the file system of the elements, to report their paths like errors do
+	fsys fs.FS
+	opts *ReadOptions
+	mu   sync.Mutex
+	list []Warning
+}
+
+func newWarnings(fsys fs.FS, opts *ReadOptions) *warnings {
+	return &warnings{fsys: fsys, opts: opts}
+}
+
+func (w *warnings) lenient() bool {
+	return w.opts != nil && w.opts.Lenient
+}
+
+func (w *warnings) add(wa Warning) {
+	wa.Path = elementPath(w.fsys, wa.Path)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.list = append(w.list, wa)
+	if w.opts.OnWarning != nil {
+		w.opts.OnWarning(wa)
+	}
+}
+
+// skip records the error as a warning and returns true in lenient mode, and returns false otherwise.
+func (w *warnings) skip(path string, err error) bool {
+	if !w.lenient() {
+		return false
+	}
+	w.add(Warning{path, err.Error(), err})
+	return true
+}
+
+// ignore returns true in lenient mode when the entry is hidden, or is not a directory with the given extension.
+// Hidden entries (.DS_Store & co.) are ignored silently, while other ones are recorded as warnings.
+func (w *warnings) ignore(path string, f fs.DirEntry, ext string) bool {
+	if !w.lenient() {
+		return false
+	}
+	if strings.HasPrefix(f.Name(), ".") {
+		return true
+	}
+	if !f.IsDir() || !strings.HasSuffix(f.Name(), ext) {
+		w.add(Warning{Path: path, Reason: "not a " + ext + " directory"})
+		return true
+	}
+	return false
+}
+
+// all returns all the warnings collected so far.
+func (w *warnings) all() []Warning {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.list
+}
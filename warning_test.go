@@ -0,0 +1,101 @@
+package quiver_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ushu/quiver"
+)
+
+// A library with some junk entries, as left by Finder, Windows or Dropbox
+var junkLibrary = fstest.MapFS{
+	"Junk.qvlibrary/.DS_Store":                              {Data: []byte("junk")},
+	"Junk.qvlibrary/Thumbs.db":                              {Data: []byte("junk")},
+	"Junk.qvlibrary/NB.qvnotebook/meta.json":                {Data: []byte(`{"name": "Notebook", "uuid": "NB"}`)},
+	"Junk.qvlibrary/NB.qvnotebook/.DS_Store":                {Data: []byte("junk")},
+	"Junk.qvlibrary/NB.qvnotebook/OK.qvnote/meta.json":      {Data: []byte(`{"title": "OK", "uuid": "OK"}`)},
+	"Junk.qvlibrary/NB.qvnotebook/OK.qvnote/content.json":   {Data: []byte(`{"cells": []}`)},
+	"Junk.qvlibrary/NB.qvnotebook/BAD.qvnote/meta.json":     {Data: []byte(`{"title": `)},
+	"Junk.qvlibrary/NB.qvnotebook/BAD.qvnote/content.json":  {Data: []byte(`{"cells": []}`)},
+	"Junk.qvlibrary/NB.qvnotebook/Partial.qvnote/meta.json": {Data: []byte(`{"title": "Partial", "uuid": "P"}`)},
+	"Junk.qvlibrary/Half.qvnotebook/Z.qvnote/meta.json":     {Data: []byte(`{"title": "Z", "uuid": "Z"}`)},
+}
+
+func TestReadLibraryStrict(t *testing.T) {
+	t.Parallel()
+
+	_, err := quiver.ReadLibraryFSWithOptions(junkLibrary, "Junk.qvlibrary", nil)
+	if err == nil {
+		t.Error("loading a library with junk entries should fail")
+	}
+}
+
+func TestReadLibraryLenient(t *testing.T) {
+	t.Parallel()
+	opts := &quiver.ReadOptions{Lenient: true}
+
+	libs := make([]*quiver.Library, 2)
+	var err error
+	libs[0], err = quiver.ReadLibraryFSWithOptions(junkLibrary, "Junk.qvlibrary", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	libs[1], err = quiver.ReadLibraryFSContext(context.Background(), junkLibrary, "Junk.qvlibrary", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, lib := range libs {
+		// Only the valid notebook & note should be loaded
+		if len(lib.Notebooks) != 1 {
+			t.Fatalf("len(lib.Notebooks) = %v; want %v", len(lib.Notebooks), 1)
+		}
+		if len(lib.Notebooks[0].Notes) != 1 || lib.Notebooks[0].Notes[0].UUID != "OK" {
+			t.Errorf("lib.Notebooks[0].Notes should only hold the %q note", "OK")
+		}
+
+		// Hidden files are silently ignored, but all other elements are reported
+		paths := make(map[string]bool)
+		for _, w := range lib.Warnings {
+			paths[w.Path] = true
+		}
+		want := []string{
+			"Junk.qvlibrary/Thumbs.db",
+			"Junk.qvlibrary/Half.qvnotebook",
+			"Junk.qvlibrary/NB.qvnotebook/BAD.qvnote",
+			"Junk.qvlibrary/NB.qvnotebook/Partial.qvnote",
+		}
+		if len(lib.Warnings) != len(want) {
+			t.Errorf("lib.Warnings = %v; want %v warnings", lib.Warnings, len(want))
+		}
+		for _, p := range want {
+			if !paths[p] {
+				t.Errorf("missing warning for %q", p)
+			}
+		}
+	}
+}
+
+func TestReadLibraryLenientOSPath(t *testing.T) {
+	t.Parallel()
+	libPath, err := filepath.Abs(copyFixture(t, "Quiver.qvlibrary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	junk := filepath.Join(libPath, "Thumbs.db")
+	if err = ioutil.WriteFile(junk, []byte("junk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the warnings give the OS path of the skipped elements, like errors
+	lib, err := quiver.ReadLibraryWithOptions(libPath, &quiver.ReadOptions{Lenient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lib.Warnings) != 1 || lib.Warnings[0].Path != junk {
+		t.Errorf("lib.Warnings = %v; want a single warning for %q", lib.Warnings, junk)
+	}
+}
@@ -0,0 +1,312 @@
+package quiver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is the polling interval used by Watch when none is given.
+const DefaultWatchInterval = 2 * time.Second
+
+// EventType tells what changed in a watched library.
+type EventType string
+
+// The types of events sent by Watch
+const (
+	NoteCreated      EventType = "note-created"
+	NoteUpdated      EventType = "note-updated"
+	NoteDeleted      EventType = "note-deleted"
+	NoteMoved        EventType = "note-moved"
+	NotebookRenamed  EventType = "notebook-renamed"
+	HierarchyChanged EventType = "hierarchy-changed"
+)
+
+// Event is a change detected in a watched library.
+type Event struct {
+	// What changed.
+	Type EventType
+	// The UUID of the note or notebook, or "" for HierarchyChanged events.
+	UUID string
+	// The path of the note or notebook directory (the previous one for deleted notes), or the path of the library for
+	// HierarchyChanged events.
+	Path string
+	// The UUID of the notebook holding the note, for note events.
+	Notebook string
+	// The UUID of the previous notebook of the note, for NoteMoved events.
+	OldNotebook string
+	// The new and previous names of the notebook, for NotebookRenamed events.
+	Name, OldName string
+}
+
+// String returns a human readable version of the event.
+func (e Event) String() string {
+	switch e.Type {
+	case HierarchyChanged:
+		return string(e.Type)
+	case NoteMoved:
+		return fmt.Sprintf("%v %v: %v -> %v", e.Type, e.UUID, e.OldNotebook, e.Notebook)
+	case NotebookRenamed:
+		return fmt.Sprintf("%v %v: %q -> %q", e.Type, e.UUID, e.OldName, e.Name)
+	default:
+		return fmt.Sprintf("%v %v", e.Type, e.UUID)
+	}
+}
+
+// Watch polls the Quiver library at the given path every interval (DefaultWatchInterval when zero), and sends the
+// changes found since the previous poll on the returned channel, until ctx is cancelled.
+//
+// Changes are detected by comparing the directory listings, the UpdatedAt field of the notes and the hashes of their
+// JSON files: no OS-specific notification is needed. Polling is lenient, as the library is edited while being
+// watched: junk files are ignored, and an element that cannot be read (like a note being written) keeps its previous
+// state until the next poll.
+//
+// An error is returned when the library cannot be read at first. The channel is closed once ctx is cancelled.
+func Watch(ctx context.Context, path string, interval time.Duration) (<-chan Event, error) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &watcher{path: path, hashes: make(map[string]fileHash)}
+	snap, err := w.scan(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			next, err := w.scan(snap)
+			if err != nil {
+				// the library may be moved or rewritten: try again later
+				continue
+			}
+			for _, e := range snap.changes(next, path) {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			snap = next
+		}
+	}()
+	return events, nil
+}
+
+// watcher polls a library, caching the hashes of the unchanged files.
+type watcher struct {
+	path   string
+	hashes map[string]fileHash
+	// the files hashed by the current scan: the other ones are forgotten at the end of the scan
+	seen map[string]bool
+}
+
+type fileHash struct {
+	size    int64
+	modTime time.Time
+	sum     [sha256.Size]byte
+}
+
+// snapshot is the state of a library at a given time.
+type snapshot struct {
+	// the hash of the library meta.json
+	hierarchy [sha256.Size]byte
+	notebooks map[string]notebookState
+	notes     map[string]noteState
+}
+
+type notebookState struct {
+	path string
+	name string
+}
+
+type noteState struct {
+	path      string
+	notebook  string
+	updatedAt time.Time
+	// the hashes of meta.json and content.json
+	meta, content [sha256.Size]byte
+}
+
+// scan reads the current state of the library, reusing the previous state of the unreadable elements.
+func (w *watcher) scan(prev *snapshot) (*snapshot, error) {
+	entries, err := os.ReadDir(w.path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(w.path, ".qvlibrary") {
+		return nil, fmt.Errorf("%w: %q should have .qvlibrary extension", ErrNotALibrary, w.path)
+	}
+
+	w.seen = make(map[string]bool, len(w.hashes))
+	defer w.prune()
+
+	snap := &snapshot{notebooks: make(map[string]notebookState), notes: make(map[string]noteState)}
+	snap.hierarchy, _ = w.hash(filepath.Join(w.path, "meta.json"))
+
+	for _, f := range entries {
+		if !f.IsDir() || !strings.HasSuffix(f.Name(), ".qvnotebook") {
+			continue
+		}
+		nbPath := filepath.Join(w.path, f.Name())
+		uuid := strings.TrimSuffix(f.Name(), ".qvnotebook")
+		nb := notebookState{path: nbPath}
+		if m, err := ReadNotebookMetadata(filepath.Join(nbPath, "meta.json")); err == nil && m.UUID != "" {
+			uuid, nb.name = m.UUID, m.Name
+		} else if prev != nil {
+			nb.name = prev.notebooks[uuid].name
+		}
+		snap.notebooks[uuid] = nb
+
+		notes, err := os.ReadDir(nbPath)
+		if err != nil {
+			// keep the notes of the notebook as they were
+			if prev != nil {
+				for id, n := range prev.notes {
+					if n.notebook == uuid {
+						snap.notes[id] = n
+					}
+				}
+			}
+			continue
+		}
+		for _, nf := range notes {
+			if !nf.IsDir() || !strings.HasSuffix(nf.Name(), ".qvnote") {
+				continue
+			}
+			id, n, ok := w.scanNote(filepath.Join(nbPath, nf.Name()), uuid)
+			if !ok {
+				if prev == nil {
+					continue
+				}
+				if n, ok = prev.notes[id]; !ok {
+					continue
+				}
+			}
+			snap.notes[id] = n
+		}
+	}
+	return snap, nil
+}
+
+// scanNote returns the UUID and state of the note, and false when it cannot be read.
+func (w *watcher) scanNote(path, notebook string) (string, noteState, bool) {
+	uuid := strings.TrimSuffix(filepath.Base(path), ".qvnote")
+	n := noteState{path: path, notebook: notebook}
+
+	data, err := os.ReadFile(filepath.Join(path, "meta.json"))
+	if err != nil {
+		return uuid, n, false
+	}
+	m, err := ParseNoteMetadata(bytes.NewReader(data))
+	if err != nil {
+		return uuid, n, false
+	}
+	if m.UUID != "" {
+		uuid = m.UUID
+	}
+	n.updatedAt = time.Time(m.UpdatedAt)
+	n.meta = sha256.Sum256(data)
+
+	n.content, err = w.hash(filepath.Join(path, "content.json"))
+	if err != nil {
+		return uuid, n, false
+	}
+	return uuid, n, true
+}
+
+// hash returns the hash of the file, only reading it again when its size or modification time changed.
+func (w *watcher) hash(path string) ([sha256.Size]byte, error) {
+	w.seen[path] = true
+	info, err := os.Stat(path)
+	if err != nil {
+		delete(w.hashes, path)
+		return [sha256.Size]byte{}, err
+	}
+	if h, ok := w.hashes[path]; ok && h.size == info.Size() && h.modTime.Equal(info.ModTime()) {
+		return h.sum, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	h := fileHash{size: info.Size(), modTime: info.ModTime(), sum: sha256.Sum256(data)}
+	w.hashes[path] = h
+	return h.sum, nil
+}
+
+// prune forgets the hashes of the files not seen by the last scan, like the ones of the deleted notes.
+func (w *watcher) prune() {
+	for path := range w.hashes {
+		if !w.seen[path] {
+			delete(w.hashes, path)
+		}
+	}
+}
+
+// changes lists the events between the two snapshots, in a stable order: the notebook renames, the hierarchy change,
+// and then the note changes sorted by UUID.
+func (s *snapshot) changes(next *snapshot, path string) []Event {
+	var events []Event
+
+	var notebooks []string
+	for uuid := range next.notebooks {
+		notebooks = append(notebooks, uuid)
+	}
+	sort.Strings(notebooks)
+	for _, uuid := range notebooks {
+		nb := next.notebooks[uuid]
+		if old, ok := s.notebooks[uuid]; ok && old.name != nb.name {
+			events = append(events, Event{Type: NotebookRenamed, UUID: uuid, Path: nb.path, Name: nb.name, OldName: old.name})
+		}
+	}
+
+	if s.hierarchy != next.hierarchy {
+		events = append(events, Event{Type: HierarchyChanged, Path: path})
+	}
+
+	var uuids []string
+	for uuid := range s.notes {
+		uuids = append(uuids, uuid)
+	}
+	for uuid := range next.notes {
+		if _, ok := s.notes[uuid]; !ok {
+			uuids = append(uuids, uuid)
+		}
+	}
+	sort.Strings(uuids)
+	for _, uuid := range uuids {
+		old, wasThere := s.notes[uuid]
+		n, isThere := next.notes[uuid]
+		switch {
+		case !wasThere:
+			events = append(events, Event{Type: NoteCreated, UUID: uuid, Path: n.path, Notebook: n.notebook})
+		case !isThere:
+			events = append(events, Event{Type: NoteDeleted, UUID: uuid, Path: old.path, Notebook: old.notebook})
+		default:
+			if old.notebook != n.notebook {
+				events = append(events, Event{Type: NoteMoved, UUID: uuid, Path: n.path, Notebook: n.notebook, OldNotebook: old.notebook})
+			}
+			if !old.updatedAt.Equal(n.updatedAt) || old.meta != n.meta || old.content != n.content {
+				events = append(events, Event{Type: NoteUpdated, UUID: uuid, Path: n.path, Notebook: n.notebook})
+			}
+		}
+	}
+	return events
+}
@@ -0,0 +1,122 @@
+package quiver_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ushu/quiver"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "Watched.qvlibrary")
+	lib := buildLibrary([]quiver.NotebookHierarchyInfo{{UUID: "NB1"}, {UUID: "NB2"}}, map[string][]*quiver.Note{
+		"NB1": {buildNote("N1", "Note", nil, "a")},
+		"NB2": {},
+	})
+	if err := quiver.WriteLibrary(path, lib); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := quiver.Watch(ctx, path, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := func(typ quiver.EventType, uuid string) quiver.Event {
+		t.Helper()
+		select {
+		case e := <-events:
+			if e.Type != typ || e.UUID != uuid {
+				t.Fatalf("got event %v; want %v %v", e, typ, uuid)
+			}
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no event; want %v %v", typ, uuid)
+		}
+		return quiver.Event{}
+	}
+	nb1, nb2 := filepath.Join(path, "NB1.qvnotebook"), filepath.Join(path, "NB2.qvnotebook")
+
+	if err := quiver.WriteNote(filepath.Join(nb1, "N2.qvnote"), buildNote("N2", "New", nil)); err != nil {
+		t.Fatal(err)
+	}
+	expect(quiver.NoteCreated, "N2")
+
+	n1 := buildNote("N1", "Updated", nil, "a")
+	if err := quiver.WriteNoteMetadata(filepath.Join(nb1, "N1.qvnote", "meta.json"), n1.NoteMetadata); err != nil {
+		t.Fatal(err)
+	}
+	expect(quiver.NoteUpdated, "N1")
+
+	if err := os.Rename(filepath.Join(nb1, "N1.qvnote"), filepath.Join(nb2, "N1.qvnote")); err != nil {
+		t.Fatal(err)
+	}
+	if e := expect(quiver.NoteMoved, "N1"); e.OldNotebook != "NB1" || e.Notebook != "NB2" {
+		t.Errorf("got event %v; want a move from NB1 to NB2", e)
+	}
+
+	if err := quiver.WriteNotebookMetadata(filepath.Join(nb2, "meta.json"), &quiver.NotebookMetadata{UUID: "NB2", Name: "Renamed"}); err != nil {
+		t.Fatal(err)
+	}
+	if e := expect(quiver.NotebookRenamed, "NB2"); e.OldName != "NB2" || e.Name != "Renamed" {
+		t.Errorf("got event %v; want a rename to %q", e, "Renamed")
+	}
+
+	hierarchy := &quiver.LibraryMetadata{Children: []quiver.NotebookHierarchyInfo{{UUID: "NB1", Children: []quiver.NotebookHierarchyInfo{{UUID: "NB2"}}}}}
+	if err := quiver.WriteLibraryMetadata(filepath.Join(path, "meta.json"), hierarchy); err != nil {
+		t.Fatal(err)
+	}
+	expect(quiver.HierarchyChanged, "")
+
+	if err := os.RemoveAll(filepath.Join(nb1, "N2.qvnote")); err != nil {
+		t.Fatal(err)
+	}
+	expect(quiver.NoteDeleted, "N2")
+
+	// the channel is closed once cancelled
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchNotALibrary(t *testing.T) {
+	t.Parallel()
+	if _, err := quiver.Watch(context.Background(), filepath.Join(t.TempDir(), "Missing.qvlibrary"), 0); err == nil {
+		t.Errorf("Watch() should fail on missing libraries")
+	}
+}
+
+func TestWatchForgetsDeletedNotes(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "Watched.qvlibrary")
+	lib := buildLibrary([]quiver.NotebookHierarchyInfo{{UUID: "NB1"}}, map[string][]*quiver.Note{
+		"NB1": {buildNote("N1", "One", nil, "a"), buildNote("N2", "Two", nil, "b")},
+	})
+	if err := quiver.WriteLibrary(path, lib); err != nil {
+		t.Fatal(err)
+	}
+
+	// the library meta.json, and the content.json of the notes
+	scan, cached := quiver.WatchCache(path)
+	if err := scan(); err != nil {
+		t.Fatal(err)
+	}
+	if cached() != 3 {
+		t.Fatalf("cached() = %v; want %v", cached(), 3)
+	}
+
+	if err := os.RemoveAll(filepath.Join(path, "NB1.qvnotebook", "N2.qvnote")); err != nil {
+		t.Fatal(err)
+	}
+	if err := scan(); err != nil {
+		t.Fatal(err)
+	}
+	if cached() != 2 {
+		t.Errorf("cached() = %v; want %v", cached(), 2)
+	}
+}
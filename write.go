@@ -0,0 +1,221 @@
+package quiver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteLibrary saves the Quiver library into the given path, which should have a .qvlibrary extension.
+// Each notebook is saved as a "<UUID>.qvnotebook" directory, and each note as a "<UUID>.qvnote" directory.
+//
+// Existing files are overwritten, but files that are not part of the library are left untouched.
+func WriteLibrary(path string, lib *Library) error {
+	if !strings.HasSuffix(path, ".qvlibrary") {
+		return fmt.Errorf("%w: %q should have .qvlibrary extension", ErrNotALibrary, path)
+	}
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return err
+	}
+
+	// when the hierarchy is unknown, all the notebooks are put at the root
+	metadata := lib.LibraryMetadata
+	if metadata == nil {
+		metadata = &LibraryMetadata{Children: make([]NotebookHierarchyInfo, len(lib.Notebooks))}
+		for i, nb := range lib.Notebooks {
+			metadata.Children[i] = NotebookHierarchyInfo{UUID: nb.UUID}
+		}
+	}
+	err = WriteLibraryMetadata(filepath.Join(path, "meta.json"), metadata)
+	if err != nil {
+		return err
+	}
+
+	for _, nb := range lib.Notebooks {
+		err = WriteNotebook(filepath.Join(path, nb.UUID+".qvnotebook"), nb)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteNotebook saves the Quiver notebook into the given path, which should have a .qvnotebook extension.
+func WriteNotebook(path string, nb *Notebook) error {
+	if !strings.HasSuffix(path, ".qvnotebook") {
+		return fmt.Errorf("%w: %q should have .qvnotebook extension", ErrNotANotebook, path)
+	}
+	if nb.NotebookMetadata == nil || nb.UUID == "" {
+		return errors.New("A Quiver Notebook should have a UUID")
+	}
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return err
+	}
+
+	err = WriteNotebookMetadata(filepath.Join(path, "meta.json"), nb.NotebookMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range nb.Notes {
+		if n.NoteMetadata == nil || n.UUID == "" {
+			return errors.New("A Quiver Note should have a UUID")
+		}
+		err = WriteNote(filepath.Join(path, n.UUID+".qvnote"), n)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteNote saves the Quiver note into the given path, which should have a .qvnote extension.
+// The resources of the note, if any, are saved in the "resources" subdirectory.
+func WriteNote(path string, note *Note) error {
+	if !strings.HasSuffix(path, ".qvnote") {
+		return fmt.Errorf("%w: %q should have .qvnote extension", ErrNotANote, path)
+	}
+	if note.NoteMetadata == nil {
+		return errors.New("A Quiver Note should have metadata")
+	}
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return err
+	}
+
+	err = WriteNoteMetadata(filepath.Join(path, "meta.json"), note.NoteMetadata)
+	if err != nil {
+		return err
+	}
+
+	content := note.NoteContent
+	if content == nil {
+		content = &NoteContent{}
+	}
+	err = WriteNoteContent(filepath.Join(path, "content.json"), note.Title, content)
+	if err != nil {
+		return err
+	}
+
+	if len(note.Resources) > 0 {
+		err = WriteNoteResources(filepath.Join(path, "resources"), note.Resources)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteNoteResources saves all the resources as files into the given directory.
+func WriteNoteResources(path string, resources []*NoteResource) error {
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range resources {
+		// resources are plain files: we don't want them to escape the directory
+		if r.Name == "" || r.Name != filepath.Base(r.Name) {
+			return errors.New("Invalid Quiver Note Resource name " + r.Name)
+		}
+		err = r.WriteFile(filepath.Join(path, r.Name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteFile copies the data of the resource into the file at the given path, which is created with mode 0644 when
+// missing, and truncated otherwise.
+func (r *NoteResource) WriteFile(path string) error {
+	in, err := r.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// a lazy resource written back onto its own file is already there (and would be truncated before being read)
+	if f, ok := in.(fs.File); ok {
+		src, serr := f.Stat()
+		dst, derr := os.Stat(path)
+		if serr == nil && derr == nil && os.SameFile(src, dst) {
+			return nil
+		}
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// WriteLibraryMetadata saves the library "meta.json" at the given path.
+func WriteLibraryMetadata(path string, m *LibraryMetadata) error {
+	aux := *m
+	if aux.Children == nil {
+		aux.Children = []NotebookHierarchyInfo{}
+	}
+	return writeJSON(path, &aux)
+}
+
+// WriteNotebookMetadata saves the notebook "meta.json" at the given path.
+func WriteNotebookMetadata(path string, m *NotebookMetadata) error {
+	return writeJSON(path, m)
+}
+
+// WriteNoteMetadata saves the note "meta.json" at the given path.
+func WriteNoteMetadata(path string, m *NoteMetadata) error {
+	aux := *m
+	if aux.Tags == nil {
+		aux.Tags = []string{}
+	}
+	return writeJSON(path, &aux)
+}
+
+// WriteNoteContent saves the note "content.json" at the given path.
+//
+// Since the Quiver app also expects the title of the note in this file, it should be provided too.
+func WriteNoteContent(path string, title string, c *NoteContent) error {
+	aux := *c
+	if aux.Cells == nil {
+		aux.Cells = []*Cell{}
+	}
+	// the given title replaces the one found when reading the file, if any
+	aux.Extra = make(map[string]json.RawMessage, len(c.Extra)+1)
+	for k, v := range c.Extra {
+		aux.Extra[k] = v
+	}
+	t, err := json.Marshal(title)
+	if err != nil {
+		return err
+	}
+	aux.Extra["title"] = t
+	return writeJSON(path, &aux)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	return ioutil.WriteFile(path, data, 0644)
+}
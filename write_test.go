@@ -0,0 +1,131 @@
+package quiver_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ushu/quiver"
+)
+
+func TestWriteLibrary(t *testing.T) {
+	t.Parallel()
+	lib, err := quiver.ReadLibrary(fixturePath("Quiver.qvlibrary"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Write the library to a new location
+	outPath := filepath.Join(t.TempDir(), "Out.qvlibrary")
+	err = quiver.WriteLibrary(outPath, lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// And load it back
+	out, err := quiver.ReadLibrary(outPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The hierarchy is created when missing
+	if len(out.Children) != 1 || out.Children[0].UUID != "FIXTURE" {
+		t.Errorf("out.Children = %v; want a single %q notebook", out.Children, "FIXTURE")
+	}
+	if len(out.Notebooks) != 1 {
+		t.Fatalf("len(out.Notebooks) = %v; want %v", len(out.Notebooks), 1)
+	}
+
+	nb, outNb := lib.Notebooks[0], out.Notebooks[0]
+	if outNb.Name != nb.Name {
+		t.Errorf("outNb.Name = %q; want %q", outNb.Name, nb.Name)
+	}
+	if len(outNb.Notes) != len(nb.Notes) {
+		t.Fatalf("len(outNb.Notes) = %v; want %v", len(outNb.Notes), len(nb.Notes))
+	}
+	for i, n := range nb.Notes {
+		outN := outNb.Notes[i]
+		if outN.UUID != n.UUID || outN.Title != n.Title {
+			t.Errorf("outNb.Notes[%v] = %q %q; want %q %q", i, outN.UUID, outN.Title, n.UUID, n.Title)
+		}
+		if !stringSliceEqual(outN.Tags, n.Tags) {
+			t.Errorf("outNb.Notes[%v].Tags = %q; want %q", i, outN.Tags, n.Tags)
+		}
+		if !timeStampEqual(outN.CreatedAt, n.CreatedAt) || !timeStampEqual(outN.UpdatedAt, n.UpdatedAt) {
+			t.Errorf("outNb.Notes[%v] timestamps differ", i)
+		}
+		if len(outN.Cells) != len(n.Cells) {
+			t.Errorf("len(outNb.Notes[%v].Cells) = %v; want %v", i, len(outN.Cells), len(n.Cells))
+		} else {
+			for j, c := range n.Cells {
+				if !reflect.DeepEqual(outN.Cells[j], c) {
+					t.Errorf("outNb.Notes[%v].Cells[%v] = %v; want %v", i, j, *outN.Cells[j], *c)
+				}
+			}
+		}
+		if len(outN.Resources) != len(n.Resources) {
+			t.Errorf("len(outNb.Notes[%v].Resources) = %v; want %v", i, len(outN.Resources), len(n.Resources))
+		} else {
+			for j, r := range n.Resources {
+				if outN.Resources[j].Name != r.Name || !bytes.Equal(outN.Resources[j].Data, r.Data) {
+					t.Errorf("outNb.Notes[%v].Resources[%v] differs", i, j)
+				}
+			}
+		}
+	}
+}
+
+func TestWriteLibraryInPlace(t *testing.T) {
+	t.Parallel()
+	// a library written by the package, so that its files have the names WriteLibrary would use
+	want, err := quiver.ReadLibrary(fixturePath("Quiver.qvlibrary"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "Quiver.qvlibrary")
+	if err = quiver.WriteLibrary(path, want); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lazy resources are already in place
+	lib, err := quiver.ReadLibraryWithOptions(path, &quiver.ReadOptions{Resources: quiver.LazyResources})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = quiver.WriteLibrary(path, lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := quiver.ReadLibrary(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range want.Notebooks[0].Notes {
+		outN := out.NoteByUUID(n.UUID)
+		if outN == nil || len(outN.Resources) != len(n.Resources) {
+			t.Fatalf("note %v lost its resources", n.UUID)
+		}
+		for j, r := range n.Resources {
+			if outN.Resources[j].Name != r.Name || !bytes.Equal(outN.Resources[j].Data, r.Data) {
+				t.Errorf("note %v: resource %v differs", n.UUID, r.Name)
+			}
+		}
+	}
+}
+
+func TestWriteNoteRequiresExtension(t *testing.T) {
+	t.Parallel()
+	note := &quiver.Note{NoteMetadata: &quiver.NoteMetadata{UUID: "NOTE"}}
+
+	err := quiver.WriteNote(filepath.Join(t.TempDir(), "NOTE"), note)
+	if err == nil {
+		t.Error("WriteNote without .qvnote extension should fail")
+	}
+}
+
+func timeStampEqual(l, r quiver.TimeStamp) bool {
+	return time.Time(l).Equal(time.Time(r))
+}